@@ -0,0 +1,179 @@
+package gcp
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Resource describes the monitored resource which produced a log entry.
+// See more at: https://cloud.google.com/logging/docs/api/v2/resource-list
+type Resource struct {
+	Type   string            `json:"type"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Environment holds the detected information about the Google Cloud environment of a service.
+type Environment struct {
+	ProjectID      string
+	Region         string
+	Zone           string
+	ServiceName    string
+	ServiceVersion string
+	Resource       Resource
+}
+
+// Detect discovers the environment from the environment variables which are set by Cloud Run,
+// Cloud Functions, App Engine and GKE and from the metadata server.
+// The metadata client can be nil, in which case only environment variables are used.
+func Detect(ctx context.Context, metadata *MetadataClient) Environment {
+	return detect(ctx, metadata, os.Getenv)
+}
+
+// lastSegment returns the last part of a metadata value like projects/123/zones/europe-west2-a.
+func lastSegment(value string) string {
+	return value[strings.LastIndex(value, "/")+1:]
+}
+
+// regionFromZone turns a zone like europe-west2-a into its region europe-west2.
+func regionFromZone(zone string) string {
+	if i := strings.LastIndex(zone, "-"); i > 0 {
+		return zone[:i]
+	}
+	return zone
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if len(v) > 0 {
+			return v
+		}
+	}
+	return ""
+}
+
+func detect(ctx context.Context, metadata *MetadataClient, getenv func(string) string) Environment {
+	query := func(path string) string {
+		if metadata == nil {
+			return ""
+		}
+		value, err := metadata.Get(ctx, path)
+		if err != nil {
+			return ""
+		}
+		return value
+	}
+
+	env := Environment{
+		ProjectID: firstNonEmpty(
+			getenv("GOOGLE_CLOUD_PROJECT"),
+			getenv("GCP_PROJECT"),
+			getenv("GCLOUD_PROJECT"),
+			query("project/project-id")),
+	}
+
+	// Serverless platforms expose their region, all others their zone.
+	regionOrZone := func() {
+		if region := lastSegment(query("instance/region")); len(region) > 0 {
+			env.Region = region
+			return
+		}
+		if zone := lastSegment(query("instance/zone")); len(zone) > 0 {
+			env.Zone = zone
+			env.Region = regionFromZone(zone)
+		}
+	}
+
+	switch {
+	case len(getenv("FUNCTION_TARGET")) > 0 || len(getenv("FUNCTION_NAME")) > 0:
+		env.ServiceName = firstNonEmpty(getenv("K_SERVICE"), getenv("FUNCTION_NAME"), getenv("FUNCTION_TARGET"))
+		env.ServiceVersion = firstNonEmpty(getenv("K_REVISION"), getenv("X_GOOGLE_FUNCTION_VERSION"))
+		regionOrZone()
+		env.Region = firstNonEmpty(env.Region, getenv("FUNCTION_REGION"))
+		env.Resource = Resource{
+			Type: "cloud_function",
+			Labels: map[string]string{
+				"function_name": env.ServiceName,
+				"region":        env.Region,
+			},
+		}
+
+	case len(getenv("K_SERVICE")) > 0:
+		env.ServiceName = getenv("K_SERVICE")
+		env.ServiceVersion = getenv("K_REVISION")
+		regionOrZone()
+		env.Resource = Resource{
+			Type: "cloud_run_revision",
+			Labels: map[string]string{
+				"service_name":       env.ServiceName,
+				"revision_name":      env.ServiceVersion,
+				"configuration_name": getenv("K_CONFIGURATION"),
+				"location":           env.Region,
+			},
+		}
+
+	case len(getenv("CLOUD_RUN_JOB")) > 0:
+		env.ServiceName = getenv("CLOUD_RUN_JOB")
+		env.ServiceVersion = getenv("CLOUD_RUN_EXECUTION")
+		regionOrZone()
+		env.Resource = Resource{
+			Type: "cloud_run_job",
+			Labels: map[string]string{
+				"job_name": env.ServiceName,
+				"location": env.Region,
+			},
+		}
+
+	case len(getenv("GAE_SERVICE")) > 0:
+		env.ServiceName = getenv("GAE_SERVICE")
+		env.ServiceVersion = getenv("GAE_VERSION")
+		regionOrZone()
+		env.Resource = Resource{
+			Type: "gae_app",
+			Labels: map[string]string{
+				"module_id":  env.ServiceName,
+				"version_id": env.ServiceVersion,
+				"zone":       firstNonEmpty(env.Zone, env.Region),
+			},
+		}
+
+	case len(getenv("KUBERNETES_SERVICE_HOST")) > 0:
+		regionOrZone()
+		namespace := firstNonEmpty(getenv("POD_NAMESPACE"), getenv("NAMESPACE"))
+		if len(namespace) == 0 {
+			if b, err := ioutil.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace"); err == nil {
+				namespace = strings.TrimSpace(string(b))
+			}
+		}
+		env.Resource = Resource{
+			Type: "k8s_container",
+			Labels: map[string]string{
+				"location":       firstNonEmpty(query("instance/attributes/cluster-location"), env.Zone, env.Region),
+				"cluster_name":   query("instance/attributes/cluster-name"),
+				"namespace_name": namespace,
+				"pod_name":       firstNonEmpty(getenv("POD_NAME"), getenv("HOSTNAME")),
+				"container_name": getenv("CONTAINER_NAME"),
+			},
+		}
+
+	default:
+		if instanceID := query("instance/id"); len(instanceID) > 0 {
+			regionOrZone()
+			env.Resource = Resource{
+				Type: "gce_instance",
+				Labels: map[string]string{
+					"instance_id": instanceID,
+					"zone":        env.Zone,
+				},
+			}
+		} else {
+			env.Resource = Resource{Type: "global", Labels: map[string]string{}}
+		}
+	}
+
+	if len(env.ProjectID) > 0 {
+		env.Resource.Labels["project_id"] = env.ProjectID
+	}
+	return env
+}
@@ -0,0 +1,148 @@
+package gcp
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func fakeEnv(values map[string]string) func(string) string {
+	return func(key string) string {
+		return values[key]
+	}
+}
+
+func Test_Detect_CloudRun(t *testing.T) {
+	server := newFakeMetadataServer(map[string]string{
+		"/computeMetadata/v1/project/project-id": "my-project",
+		"/computeMetadata/v1/instance/region":    "projects/123456/regions/europe-west2",
+	})
+	defer server.Close()
+	metadata := NewMetadataClient(server.URL+"/computeMetadata/v1", nil)
+
+	env := detect(context.Background(), metadata, fakeEnv(map[string]string{
+		"K_SERVICE":       "api",
+		"K_REVISION":      "api-00042-abc",
+		"K_CONFIGURATION": "api",
+	}))
+
+	expected := Environment{
+		ProjectID:      "my-project",
+		Region:         "europe-west2",
+		ServiceName:    "api",
+		ServiceVersion: "api-00042-abc",
+		Resource: Resource{
+			Type: "cloud_run_revision",
+			Labels: map[string]string{
+				"project_id":         "my-project",
+				"service_name":       "api",
+				"revision_name":      "api-00042-abc",
+				"configuration_name": "api",
+				"location":           "europe-west2",
+			},
+		},
+	}
+	if !reflect.DeepEqual(env, expected) {
+		t.Errorf("\nExpected:\n%+v,\nActual:\n%+v", expected, env)
+	}
+}
+
+func Test_Detect_CloudFunctions(t *testing.T) {
+	env := detect(context.Background(), nil, fakeEnv(map[string]string{
+		"GOOGLE_CLOUD_PROJECT": "fn-project",
+		"FUNCTION_TARGET":      "HandleEvent",
+		"K_SERVICE":            "handle-event",
+		"K_REVISION":           "7",
+		"FUNCTION_REGION":      "us-central1",
+	}))
+
+	if env.ServiceName != "handle-event" || env.ServiceVersion != "7" || env.Region != "us-central1" {
+		t.Errorf("Unexpected environment: %+v", env)
+	}
+	if env.Resource.Type != "cloud_function" || env.Resource.Labels["function_name"] != "handle-event" || env.Resource.Labels["project_id"] != "fn-project" {
+		t.Errorf("Unexpected resource: %+v", env.Resource)
+	}
+}
+
+func Test_Detect_AppEngine(t *testing.T) {
+	server := newFakeMetadataServer(map[string]string{
+		"/computeMetadata/v1/instance/zone": "projects/123456/zones/us-east1-b",
+	})
+	defer server.Close()
+	metadata := NewMetadataClient(server.URL+"/computeMetadata/v1", nil)
+
+	env := detect(context.Background(), metadata, fakeEnv(map[string]string{
+		"GOOGLE_CLOUD_PROJECT": "gae-project",
+		"GAE_SERVICE":          "default",
+		"GAE_VERSION":          "20210101t000000",
+	}))
+
+	if env.Zone != "us-east1-b" || env.Region != "us-east1" {
+		t.Errorf("Unexpected zone or region: %q, %q", env.Zone, env.Region)
+	}
+	if env.Resource.Type != "gae_app" || env.Resource.Labels["module_id"] != "default" || env.Resource.Labels["zone"] != "us-east1-b" {
+		t.Errorf("Unexpected resource: %+v", env.Resource)
+	}
+}
+
+func Test_Detect_Kubernetes(t *testing.T) {
+	server := newFakeMetadataServer(map[string]string{
+		"/computeMetadata/v1/project/project-id":                   "gke-project",
+		"/computeMetadata/v1/instance/zone":                        "projects/123456/zones/europe-west1-c",
+		"/computeMetadata/v1/instance/attributes/cluster-name":     "prod",
+		"/computeMetadata/v1/instance/attributes/cluster-location": "europe-west1",
+	})
+	defer server.Close()
+	metadata := NewMetadataClient(server.URL+"/computeMetadata/v1", nil)
+
+	env := detect(context.Background(), metadata, fakeEnv(map[string]string{
+		"KUBERNETES_SERVICE_HOST": "10.0.0.1",
+		"POD_NAMESPACE":           "shop",
+		"HOSTNAME":                "checkout-5d8f7",
+		"CONTAINER_NAME":          "checkout",
+	}))
+
+	expected := map[string]string{
+		"project_id":     "gke-project",
+		"location":       "europe-west1",
+		"cluster_name":   "prod",
+		"namespace_name": "shop",
+		"pod_name":       "checkout-5d8f7",
+		"container_name": "checkout",
+	}
+	if env.Resource.Type != "k8s_container" || !reflect.DeepEqual(env.Resource.Labels, expected) {
+		t.Errorf("Unexpected resource: %+v", env.Resource)
+	}
+}
+
+func Test_Detect_ComputeEngine(t *testing.T) {
+	server := newFakeMetadataServer(map[string]string{
+		"/computeMetadata/v1/project/project-id": "gce-project",
+		"/computeMetadata/v1/instance/id":        "4520031799277581759",
+		"/computeMetadata/v1/instance/zone":      "projects/123456/zones/asia-east1-a",
+	})
+	defer server.Close()
+	metadata := NewMetadataClient(server.URL+"/computeMetadata/v1", nil)
+
+	env := detect(context.Background(), metadata, fakeEnv(nil))
+
+	expected := Resource{
+		Type: "gce_instance",
+		Labels: map[string]string{
+			"project_id":  "gce-project",
+			"instance_id": "4520031799277581759",
+			"zone":        "asia-east1-a",
+		},
+	}
+	if !reflect.DeepEqual(env.Resource, expected) {
+		t.Errorf("\nExpected:\n%+v,\nActual:\n%+v", expected, env.Resource)
+	}
+}
+
+func Test_Detect_FallsBackToGlobalResource(t *testing.T) {
+	env := detect(context.Background(), nil, fakeEnv(nil))
+
+	if env.Resource.Type != "global" || len(env.Resource.Labels) != 0 || env.ProjectID != "" {
+		t.Errorf("Unexpected environment: %+v", env)
+	}
+}
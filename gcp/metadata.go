@@ -0,0 +1,98 @@
+// Package gcp discovers information about the Google Cloud environment which a service runs in.
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultMetadataURL is the base URL of the Google Compute Engine metadata server.
+const DefaultMetadataURL = "http://metadata.google.internal/computeMetadata/v1"
+
+// MetadataClient queries the Google Compute Engine metadata server.
+// See more at: https://cloud.google.com/compute/docs/metadata/overview
+type MetadataClient struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewMetadataClient creates a new client for the metadata server at the given base URL.
+// An empty base URL defaults to the GCE_METADATA_HOST environment variable or DefaultMetadataURL.
+func NewMetadataClient(baseURL string, client *http.Client) *MetadataClient {
+	if len(baseURL) == 0 {
+		baseURL = DefaultMetadataURL
+		if host := os.Getenv("GCE_METADATA_HOST"); len(host) > 0 {
+			baseURL = "http://" + host + "/computeMetadata/v1"
+		}
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 2 * time.Second}
+	}
+	return &MetadataClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  client,
+	}
+}
+
+// Get returns the value of a metadata path, e.g. "project/project-id".
+func (c *MetadataClient) Get(ctx context.Context, path string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+"/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return "", fmt.Errorf("error creating metadata request: %w", err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error querying metadata server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return "", fmt.Errorf("error reading metadata response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata server responded with status %d for %s", resp.StatusCode, path)
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+// ProjectID returns the ID of the project which the service runs in.
+func (c *MetadataClient) ProjectID(ctx context.Context) (string, error) {
+	return c.Get(ctx, "project/project-id")
+}
+
+// ProjectIDFromEnv returns the project ID from the GOOGLE_CLOUD_PROJECT,
+// GCP_PROJECT or GCLOUD_PROJECT environment variables.
+func ProjectIDFromEnv() string {
+	for _, key := range []string{"GOOGLE_CLOUD_PROJECT", "GCP_PROJECT", "GCLOUD_PROJECT"} {
+		if value := os.Getenv(key); len(value) > 0 {
+			return value
+		}
+	}
+	return ""
+}
+
+// DetectProjectID returns the project ID from the environment or otherwise from the metadata server.
+// It returns an empty string if the project ID cannot be discovered.
+func DetectProjectID(ctx context.Context, metadata *MetadataClient) string {
+	if projectID := ProjectIDFromEnv(); len(projectID) > 0 {
+		return projectID
+	}
+	if metadata == nil {
+		return ""
+	}
+	projectID, err := metadata.ProjectID(ctx)
+	if err != nil {
+		return ""
+	}
+	return projectID
+}
@@ -0,0 +1,82 @@
+package gcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func newFakeMetadataServer(values map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		value, ok := values[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(value))
+	}))
+}
+
+func clearProjectEnv(t *testing.T) {
+	for _, key := range []string{"GOOGLE_CLOUD_PROJECT", "GCP_PROJECT", "GCLOUD_PROJECT"} {
+		value, ok := os.LookupEnv(key)
+		os.Unsetenv(key)
+		if ok {
+			key := key
+			t.Cleanup(func() { os.Setenv(key, value) })
+		}
+	}
+}
+
+func Test_MetadataClient_ProjectID(t *testing.T) {
+	server := newFakeMetadataServer(map[string]string{
+		"/computeMetadata/v1/project/project-id": "my-project\n",
+	})
+	defer server.Close()
+
+	metadata := NewMetadataClient(server.URL+"/computeMetadata/v1/", nil)
+
+	projectID, err := metadata.ProjectID(context.Background())
+	if err != nil || projectID != "my-project" {
+		t.Errorf("Expected my-project, but got %q (%v)", projectID, err)
+	}
+
+	if _, err := metadata.Get(context.Background(), "instance/zone"); err == nil {
+		t.Error("Expected an error for a missing metadata path.")
+	}
+}
+
+func Test_DetectProjectID_PrefersEnvironment(t *testing.T) {
+	clearProjectEnv(t)
+	server := newFakeMetadataServer(map[string]string{
+		"/computeMetadata/v1/project/project-id": "metadata-project",
+	})
+	defer server.Close()
+	metadata := NewMetadataClient(server.URL+"/computeMetadata/v1", nil)
+
+	if actual := DetectProjectID(context.Background(), metadata); actual != "metadata-project" {
+		t.Errorf("Expected metadata-project, but got %q", actual)
+	}
+
+	os.Setenv("GOOGLE_CLOUD_PROJECT", "env-project")
+	defer os.Unsetenv("GOOGLE_CLOUD_PROJECT")
+	if actual := DetectProjectID(context.Background(), metadata); actual != "env-project" {
+		t.Errorf("Expected env-project, but got %q", actual)
+	}
+}
+
+func Test_DetectProjectID_ReturnsEmptyStringWithoutMetadataServer(t *testing.T) {
+	clearProjectEnv(t)
+	server := newFakeMetadataServer(map[string]string{})
+	defer server.Close()
+
+	if actual := DetectProjectID(context.Background(), NewMetadataClient(server.URL, nil)); actual != "" {
+		t.Errorf("Expected an empty project ID, but got %q", actual)
+	}
+}
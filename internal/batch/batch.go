@@ -0,0 +1,254 @@
+// Package batch buffers items in memory and writes them in batches from a background goroutine.
+// It holds the batching, retry and shutdown logic of the HTTP exporters.
+package batch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Options configures the batching and retry behaviour of a Batcher.
+// Zero values are replaced with sensible defaults.
+type Options struct {
+	// BatchSize is the number of items which triggers a write (default: 100).
+	BatchSize int
+	// MaxBuffered is the maximum number of items waiting to be written.
+	// Further items are dropped until the buffer has been written (default: 10 * BatchSize).
+	MaxBuffered int
+	// FlushInterval is the maximum time an item waits before it gets written (default: 5s).
+	FlushInterval time.Duration
+	// MaxRetries is the number of times a failed write gets retried (default: 3, negative disables retries).
+	MaxRetries int
+	// RetryBackoff is the wait time before the first retry, which doubles with every further retry (default: 500ms).
+	RetryBackoff time.Duration
+}
+
+func (o Options) withDefaults() Options {
+	if o.BatchSize < 1 {
+		o.BatchSize = 100
+	}
+	if o.MaxBuffered < o.BatchSize {
+		o.MaxBuffered = 10 * o.BatchSize
+	}
+	if o.FlushInterval <= 0 {
+		o.FlushInterval = 5 * time.Second
+	}
+	if o.MaxRetries < 0 {
+		o.MaxRetries = 0
+	} else if o.MaxRetries == 0 {
+		o.MaxRetries = 3
+	}
+	if o.RetryBackoff <= 0 {
+		o.RetryBackoff = 500 * time.Millisecond
+	}
+	return o
+}
+
+// SendFunc writes a batch of items.
+type SendFunc func(ctx context.Context, items []interface{}) error
+
+// Batcher collects items and hands them in batches to a SendFunc, once a batch is full,
+// after the flush interval or on Flush and Close.
+type Batcher struct {
+	options Options
+	send    SendFunc
+	failure string
+
+	mutex   sync.Mutex
+	batch   []interface{}
+	dropped uint64
+	closed  bool
+	signal  chan struct{}
+	stop    chan struct{}
+	stopped chan struct{}
+
+	// sendMutex makes sure that batches get written one at a time and in order.
+	sendMutex sync.Mutex
+}
+
+// New creates a new Batcher and starts its background goroutine.
+// Errors of the background writes are printed to stderr, prefixed with the failure message.
+func New(options Options, send SendFunc, failure string) *Batcher {
+	b := &Batcher{
+		options: options.withDefaults(),
+		send:    send,
+		failure: failure,
+		signal:  make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+func (b *Batcher) run() {
+	defer close(b.stopped)
+	ticker := time.NewTicker(b.options.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+		case <-b.signal:
+		}
+		b.report(b.Flush(context.Background()))
+	}
+}
+
+func (b *Batcher) report(err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", b.failure, err)
+	}
+}
+
+// Add adds an item to the current batch or drops it if the buffer is full.
+// Items which get added after Close are written immediately.
+func (b *Batcher) Add(item interface{}) {
+	b.mutex.Lock()
+	if b.closed {
+		b.mutex.Unlock()
+		b.report(b.send(context.Background(), []interface{}{item}))
+		return
+	}
+	defer b.mutex.Unlock()
+
+	if len(b.batch) >= b.options.MaxBuffered {
+		b.dropped++
+		return
+	}
+	b.batch = append(b.batch, item)
+
+	if len(b.batch) >= b.options.BatchSize {
+		select {
+		case b.signal <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Dropped returns the number of items which have been discarded due to a full buffer.
+func (b *Batcher) Dropped() uint64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.dropped
+}
+
+// Flush writes all buffered items in batches.
+// Items of a batch which could not be written are discarded.
+func (b *Batcher) Flush(ctx context.Context) error {
+	b.sendMutex.Lock()
+	defer b.sendMutex.Unlock()
+
+	for {
+		b.mutex.Lock()
+		n := len(b.batch)
+		if n > b.options.BatchSize {
+			n = b.options.BatchSize
+		}
+		batch := b.batch[:n]
+		b.batch = b.batch[n:]
+		b.mutex.Unlock()
+
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := b.send(ctx, batch); err != nil {
+			return err
+		}
+	}
+}
+
+// Close stops the background flushing and writes all remaining items.
+func (b *Batcher) Close(ctx context.Context) error {
+	b.mutex.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.stop)
+	}
+	b.mutex.Unlock()
+
+	select {
+	case <-b.stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return b.Flush(ctx)
+}
+
+// Request describes the HTTP endpoint which batches get posted to.
+type Request struct {
+	Client *http.Client
+	// Name identifies the endpoint in error messages, e.g. "Cloud Logging".
+	Name   string
+	URL    string
+	Header http.Header
+	// Retryable reports if a failed request with the given status can be retried.
+	// Nil retries 429 Too Many Requests and all 5xx responses.
+	Retryable func(status int) bool
+}
+
+// Post posts a JSON body and retries failed requests with an exponential backoff.
+func (b *Batcher) Post(ctx context.Context, r Request, body []byte) error {
+	backoff := b.options.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := r.post(ctx, body)
+		if err == nil || !retry || attempt >= b.options.MaxRetries {
+			return err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// post sends a single request and reports if a failed request can be retried.
+func (r Request) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, r.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("error creating %s request: %w", r.Name, err)
+	}
+	req = req.WithContext(ctx)
+	for key, values := range r.Header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, fmt.Errorf("error sending %s request: %w", r.Name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		return false, nil
+	}
+
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+	retryable := r.Retryable
+	if retryable == nil {
+		retryable = retryableStatus
+	}
+	return retryable(resp.StatusCode), fmt.Errorf("%s responded with status %d: %s", r.Name, resp.StatusCode, bytes.TrimSpace(msg))
+}
+
+func retryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
@@ -0,0 +1,130 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// recorder records the batches which get sent.
+type recorder struct {
+	mutex   sync.Mutex
+	batches [][]interface{}
+}
+
+func (r *recorder) send(_ context.Context, items []interface{}) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.batches = append(r.batches, append([]interface{}(nil), items...))
+	return nil
+}
+
+func (r *recorder) result() [][]interface{} {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([][]interface{}(nil), r.batches...)
+}
+
+func Test_Batcher_Close_WritesItemsInBatches(t *testing.T) {
+	r := &recorder{}
+	b := New(Options{BatchSize: 2, FlushInterval: time.Hour}, r.send, "test")
+	for i := 0; i < 5; i++ {
+		b.Add(i)
+	}
+	if err := b.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	b.Add(5)
+
+	total := 0
+	for _, batch := range r.result() {
+		if len(batch) > 2 {
+			t.Errorf("Expected at most 2 items per batch, but got %d.", len(batch))
+		}
+		total += len(batch)
+	}
+	if total != 6 {
+		t.Errorf("Expected 6 items, but got %d.", total)
+	}
+}
+
+func Test_Batcher_MaxBuffered_DropsItems(t *testing.T) {
+	r := &recorder{}
+	b := New(Options{BatchSize: 2, MaxBuffered: 2, FlushInterval: time.Hour}, r.send, "test")
+	defer b.Close(context.Background())
+
+	// Hold the send lock so that the background flush cannot empty the buffer.
+	b.sendMutex.Lock()
+	for i := 0; i < 5; i++ {
+		b.Add(i)
+	}
+	b.sendMutex.Unlock()
+
+	if dropped := b.Dropped(); dropped != 3 {
+		t.Errorf("Expected 3 dropped items, but got %d.", dropped)
+	}
+}
+
+func Test_Batcher_Flush_ReturnsSendErrors(t *testing.T) {
+	b := New(Options{FlushInterval: time.Hour}, func(context.Context, []interface{}) error {
+		return errors.New("failed")
+	}, "test")
+	defer b.Close(context.Background())
+
+	b.Add(1)
+	if err := b.Flush(context.Background()); err == nil || err.Error() != "failed" {
+		t.Errorf("Expected the send error, but got %v", err)
+	}
+}
+
+func Test_Batcher_Post_RetriesRetryableStatus(t *testing.T) {
+	type testCase struct {
+		Status    int
+		Retryable func(int) bool
+		Attempts  int32
+	}
+
+	testCases := []testCase{
+		{http.StatusServiceUnavailable, nil, 3},
+		{http.StatusTooManyRequests, nil, 3},
+		{http.StatusForbidden, nil, 1},
+		{http.StatusInternalServerError, func(status int) bool { return status == http.StatusBadGateway }, 1},
+	}
+
+	for _, testCase := range testCases {
+		var attempts int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			if r.Header.Get("Content-Type") != "application/json" || r.Header.Get("X-Key") != "secret" {
+				t.Errorf("Unexpected headers: %v", r.Header)
+			}
+			w.WriteHeader(testCase.Status)
+			_, _ = w.Write([]byte("nope"))
+		}))
+
+		b := New(Options{FlushInterval: time.Hour, MaxRetries: 2, RetryBackoff: time.Millisecond}, nil, "test")
+		req := Request{
+			Client:    server.Client(),
+			Name:      "Test API",
+			URL:       server.URL,
+			Header:    http.Header{"X-Key": []string{"secret"}},
+			Retryable: testCase.Retryable,
+		}
+		err := b.Post(context.Background(), req, []byte("{}"))
+		if err == nil || !strings.HasPrefix(err.Error(), "Test API responded with status") {
+			t.Errorf("Status %d: Expected an API error, but got %v", testCase.Status, err)
+		}
+		if actual := atomic.LoadInt32(&attempts); actual != testCase.Attempts {
+			t.Errorf("Status %d: Expected %d attempts, but got %d.", testCase.Status, testCase.Attempts, actual)
+		}
+
+		_ = b.Close(context.Background())
+		server.Close()
+	}
+}
@@ -0,0 +1,260 @@
+package log
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy decides what happens when the queue of an AsyncExporter is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks the caller until there is space in the queue.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest discards the log message which is about to be queued.
+	OverflowDropNewest
+	// OverflowDropOldest discards the oldest queued log message to make space for the new one.
+	OverflowDropOldest
+	// OverflowDropByLevel discards the log message which is about to be queued
+	// if its level is below the exporter's drop level and blocks otherwise.
+	OverflowDropByLevel
+)
+
+type asyncItem struct {
+	entry    Entry
+	hasEntry bool
+	output   string
+}
+
+// AsyncExporter wraps another exporter and emits log messages from a background goroutine.
+// Log messages are put on a bounded queue so that a slow output source doesn't stall the caller.
+// Calls to the wrapped exporter are serialised, also when Close gave up waiting for the queue
+// and log messages which get exported after Close are written synchronously.
+type AsyncExporter struct {
+	// dropped is accessed atomically and must stay 64-bit aligned.
+	dropped uint64
+
+	exporter  Exporter
+	policy    OverflowPolicy
+	dropLevel Level
+	queue     chan asyncItem
+	closing   chan struct{}
+	done      chan struct{}
+
+	// exportMutex serialises the calls to the wrapped exporter.
+	exportMutex sync.Mutex
+
+	// mutex guards closed, which stops senders from being added after Close.
+	// It is never held while waiting for space in the queue.
+	mutex   sync.RWMutex
+	closed  bool
+	senders sync.WaitGroup
+
+	// pendingMutex guards pending and waiters which are used by Flush.
+	pendingMutex sync.Mutex
+	pending      int
+	waiters      []chan struct{}
+}
+
+// NewAsyncExporter creates a new AsyncExporter which forwards log messages to the given exporter.
+// The dropLevel is only used by the OverflowDropByLevel policy.
+func NewAsyncExporter(exporter Exporter, queueSize int, policy OverflowPolicy, dropLevel Level) *AsyncExporter {
+	if exporter == nil {
+		exporter = &StdoutExporter{}
+	}
+
+	if queueSize < 1 {
+		queueSize = 1
+	}
+
+	e := &AsyncExporter{
+		exporter:  exporter,
+		policy:    policy,
+		dropLevel: dropLevel,
+		queue:     make(chan asyncItem, queueSize),
+		closing:   make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+func (e *AsyncExporter) run() {
+	defer close(e.done)
+	for {
+		select {
+		case item := <-e.queue:
+			e.emit(item)
+			e.release()
+		case <-e.closing:
+			// Senders which were waiting for space in the queue give up once
+			// the exporter is closing, after that the queue can be drained.
+			e.senders.Wait()
+			for {
+				select {
+				case item := <-e.queue:
+					e.emit(item)
+					e.release()
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (e *AsyncExporter) emit(item asyncItem) {
+	e.exportMutex.Lock()
+	defer e.exportMutex.Unlock()
+	if item.hasEntry {
+		export(e.exporter, item.entry, item.output)
+	} else {
+		e.exporter.Export(item.output)
+	}
+}
+
+func (e *AsyncExporter) release() {
+	e.pendingMutex.Lock()
+	defer e.pendingMutex.Unlock()
+	e.pending--
+	if e.pending == 0 {
+		for _, w := range e.waiters {
+			close(w)
+		}
+		e.waiters = nil
+	}
+}
+
+func (e *AsyncExporter) drop() {
+	atomic.AddUint64(&e.dropped, 1)
+	e.release()
+}
+
+// send blocks until the item is queued or the exporter is closing,
+// in which case the item is written synchronously.
+func (e *AsyncExporter) send(item asyncItem) {
+	select {
+	case e.queue <- item:
+	case <-e.closing:
+		e.emit(item)
+		e.release()
+	}
+}
+
+func (e *AsyncExporter) enqueue(item asyncItem, level Level) {
+	e.mutex.RLock()
+	if e.closed {
+		e.mutex.RUnlock()
+		// Don't lose log messages which get written during or after shutdown.
+		e.emit(item)
+		return
+	}
+	e.senders.Add(1)
+	e.mutex.RUnlock()
+	defer e.senders.Done()
+
+	e.pendingMutex.Lock()
+	e.pending++
+	e.pendingMutex.Unlock()
+
+	select {
+	case e.queue <- item:
+		return
+	default:
+	}
+
+	switch e.policy {
+	case OverflowDropNewest:
+		e.drop()
+	case OverflowDropByLevel:
+		if level < e.dropLevel {
+			e.drop()
+			return
+		}
+		e.send(item)
+	case OverflowDropOldest:
+		for {
+			select {
+			case e.queue <- item:
+				return
+			default:
+			}
+			select {
+			case <-e.queue:
+				e.drop()
+			default:
+			}
+		}
+	default:
+		e.send(item)
+	}
+}
+
+// Export queues a log message without a known log level.
+// The OverflowDropByLevel policy treats such messages as the Default level.
+func (e *AsyncExporter) Export(output string) {
+	e.enqueue(asyncItem{output: output}, Default)
+}
+
+// ExportEntry queues a log message together with its entry.
+func (e *AsyncExporter) ExportEntry(entry Entry, output string) {
+	e.enqueue(asyncItem{entry: entry, hasEntry: true, output: output}, entry.Level)
+}
+
+// Dropped returns the number of log messages which have been discarded due to a full queue.
+func (e *AsyncExporter) Dropped() uint64 {
+	return atomic.LoadUint64(&e.dropped)
+}
+
+// Flush blocks until all queued log messages have been emitted or the context expires.
+// If the wrapped exporter implements Flusher then it gets flushed as well.
+func (e *AsyncExporter) Flush(ctx context.Context) error {
+	e.pendingMutex.Lock()
+	if e.pending == 0 {
+		e.pendingMutex.Unlock()
+		return e.flushExporter(ctx)
+	}
+	w := make(chan struct{})
+	e.waiters = append(e.waiters, w)
+	e.pendingMutex.Unlock()
+
+	select {
+	case <-w:
+		return e.flushExporter(ctx)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *AsyncExporter) flushExporter(ctx context.Context) error {
+	if f, ok := e.exporter.(Flusher); ok {
+		return f.Flush(ctx)
+	}
+	return nil
+}
+
+// Close stops accepting new log messages into the queue and blocks until all
+// queued log messages have been emitted or the context expires.
+// Log messages which get exported after Close, or which were waiting for space
+// in a full queue, are written synchronously.
+// If the wrapped exporter implements Closer then it gets closed as well.
+func (e *AsyncExporter) Close(ctx context.Context) error {
+	e.mutex.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.closing)
+	}
+	e.mutex.Unlock()
+
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if c, ok := e.exporter.(Closer); ok {
+		return c.Close(ctx)
+	}
+	return e.flushExporter(ctx)
+}
@@ -0,0 +1,210 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// gatedExporter blocks every export until the gate gets opened.
+type gatedExporter struct {
+	gate    chan struct{}
+	mutex   sync.Mutex
+	outputs []string
+	closed  bool
+}
+
+func newGatedExporter() *gatedExporter {
+	return &gatedExporter{gate: make(chan struct{})}
+}
+
+func (e *gatedExporter) Export(output string) {
+	<-e.gate
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.outputs = append(e.outputs, output)
+}
+
+func (e *gatedExporter) Close(_ context.Context) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.closed = true
+	return nil
+}
+
+func (e *gatedExporter) result() []string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return append([]string(nil), e.outputs...)
+}
+
+func Test_AsyncExporter_Flush_EmitsAllMessagesInOrder(t *testing.T) {
+	target := newGatedExporter()
+	close(target.gate)
+	exporter := NewAsyncExporter(target, 100, OverflowBlock, Default)
+
+	for i := 0; i < 50; i++ {
+		exporter.Export(fmt.Sprintf("%d", i))
+	}
+
+	if err := exporter.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	outputs := target.result()
+	if len(outputs) != 50 {
+		t.Fatalf("Expected 50 messages, but got %d.", len(outputs))
+	}
+	for i, output := range outputs {
+		if output != fmt.Sprintf("%d", i) {
+			t.Errorf("Expected message %d at position %d, but got %s.", i, i, output)
+		}
+	}
+}
+
+func Test_AsyncExporter_Flush_ReturnsWhenContextExpires(t *testing.T) {
+	target := newGatedExporter()
+	exporter := NewAsyncExporter(target, 10, OverflowBlock, Default)
+	exporter.Export("stuck")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := exporter.Flush(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected a deadline exceeded error, but got %v.", err)
+	}
+	close(target.gate)
+}
+
+func Test_AsyncExporter_OverflowPolicies_DropCorrectMessages(t *testing.T) {
+	type testCase struct {
+		Policy   OverflowPolicy
+		Entries  []Entry
+		Expected []string
+	}
+
+	testCases := []testCase{
+		{
+			OverflowDropNewest,
+			[]Entry{{Message: "1"}, {Message: "2"}, {Message: "3"}, {Message: "4"}},
+			[]string{"1", "2", "3"},
+		},
+		{
+			OverflowDropOldest,
+			[]Entry{{Message: "1"}, {Message: "2"}, {Message: "3"}, {Message: "4"}},
+			[]string{"1", "3", "4"},
+		},
+		{
+			OverflowDropByLevel,
+			[]Entry{{Message: "1", Level: Error}, {Message: "2", Level: Info}, {Message: "3", Level: Info}, {Message: "4", Level: Debug}},
+			[]string{"1", "2", "3"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		target := newGatedExporter()
+		exporter := NewAsyncExporter(target, 2, testCase.Policy, Warning)
+
+		// The first message gets picked up by the background goroutine
+		// and blocks until the gate opens, the next two fill the queue.
+		exporter.ExportEntry(testCase.Entries[0], testCase.Entries[0].Message)
+		for len(exporter.queue) > 0 {
+			time.Sleep(time.Millisecond)
+		}
+		for _, entry := range testCase.Entries[1:] {
+			exporter.ExportEntry(entry, entry.Message)
+		}
+
+		close(target.gate)
+		if err := exporter.Close(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		actual := target.result()
+		if fmt.Sprint(actual) != fmt.Sprint(testCase.Expected) {
+			t.Errorf("\nPolicy %d\nExpected:\n%v,\nActual:\n%v", testCase.Policy, testCase.Expected, actual)
+		}
+		if exporter.Dropped() != 1 {
+			t.Errorf("Policy %d: Expected 1 dropped message, but got %d.", testCase.Policy, exporter.Dropped())
+		}
+	}
+}
+
+func Test_AsyncExporter_Close_DrainsQueueAndClosesExporter(t *testing.T) {
+	target := newGatedExporter()
+	exporter := NewAsyncExporter(target, 10, OverflowBlock, Default)
+	exporter.Export("a")
+	exporter.Export("b")
+	close(target.gate)
+
+	if err := exporter.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	exporter.Export("after close")
+
+	if actual := target.result(); fmt.Sprint(actual) != "[a b after close]" {
+		t.Errorf("Unexpected messages: %v", actual)
+	}
+	if !target.closed {
+		t.Error("Expected the wrapped exporter to be closed.")
+	}
+}
+
+// concurrencyExporter records the highest number of concurrent exports.
+type concurrencyExporter struct {
+	*gatedExporter
+	active  int32
+	maximum int32
+}
+
+func (e *concurrencyExporter) Export(output string) {
+	active := atomic.AddInt32(&e.active, 1)
+	defer atomic.AddInt32(&e.active, -1)
+	for {
+		maximum := atomic.LoadInt32(&e.maximum)
+		if active <= maximum || atomic.CompareAndSwapInt32(&e.maximum, maximum, active) {
+			break
+		}
+	}
+	e.gatedExporter.Export(output)
+}
+
+func Test_AsyncExporter_Close_ReturnsWhenSinkIsStuck(t *testing.T) {
+	target := &concurrencyExporter{gatedExporter: newGatedExporter()}
+	exporter := NewAsyncExporter(target, 1, OverflowBlock, Default)
+	exporter.Export("a")
+	for atomic.LoadInt32(&target.active) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	exporter.Export("b")
+
+	blocked := make(chan struct{})
+	go func() {
+		exporter.Export("c")
+		close(blocked)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := exporter.Close(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Expected a deadline exceeded error, but got: %v", err)
+	}
+
+	close(target.gate)
+	<-blocked
+	exporter.Export("d")
+
+	if err := exporter.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if actual := target.result(); len(actual) != 4 {
+		t.Errorf("Unexpected messages: %v", actual)
+	}
+	if maximum := atomic.LoadInt32(&target.maximum); maximum != 1 {
+		t.Errorf("Expected the wrapped exporter to be called sequentially, but got %d concurrent calls.", maximum)
+	}
+}
@@ -0,0 +1,17 @@
+package log
+
+import "runtime"
+
+// callerLocation returns the source location of the caller skip frames above the function which
+// calls callerLocation, or nil if it cannot be determined.
+func callerLocation(skip int) *SourceLocation {
+	pc, file, line, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return nil
+	}
+	location := &SourceLocation{File: file, Line: line}
+	if fn := runtime.FuncForPC(pc); fn != nil {
+		location.Function = fn.Name()
+	}
+	return location
+}
@@ -0,0 +1,210 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/dusted-go/diagnostic/gcp"
+	"github.com/dusted-go/diagnostic/internal/batch"
+)
+
+// DefaultCloudLoggingEndpoint is the URL of the Google Cloud Logging entries:write API.
+const DefaultCloudLoggingEndpoint = "https://logging.googleapis.com/v2/entries:write"
+
+// Resource describes the monitored resource which produced a log entry.
+// Use gcp.Detect to discover the resource of the current environment.
+type Resource = gcp.Resource
+
+// CloudLoggingOptions configures the batching and retry behaviour of a CloudLoggingExporter.
+// Zero values are replaced with sensible defaults.
+type CloudLoggingOptions struct {
+	// Endpoint is the URL of the entries:write API (default: DefaultCloudLoggingEndpoint).
+	Endpoint string
+	// BatchSize is the number of entries which triggers a write (default: 100).
+	BatchSize int
+	// MaxBufferedEntries is the maximum number of entries waiting to be written.
+	// Further entries are dropped until the buffer has been written (default: 10 * BatchSize).
+	MaxBufferedEntries int
+	// FlushInterval is the maximum time an entry waits before it gets written (default: 5s).
+	FlushInterval time.Duration
+	// MaxRetries is the number of times a failed write gets retried (default: 3, negative disables retries).
+	MaxRetries int
+	// RetryBackoff is the wait time before the first retry, which doubles with every further retry (default: 500ms).
+	RetryBackoff time.Duration
+}
+
+// CloudLoggingExporter writes log entries directly to the Google Cloud Logging API.
+// It is meant for environments without a logging agent which scrapes stdout.
+//
+// The given HTTP client must authenticate its requests,
+// for example a client from golang.org/x/oauth2/google.DefaultClient.
+type CloudLoggingExporter struct {
+	projectID string
+	logName   string
+	resource  Resource
+	request   batch.Request
+	batcher   *batch.Batcher
+}
+
+// NewCloudLoggingExporter creates a new CloudLoggingExporter which writes
+// to the log projects/{projectID}/logs/{logID} of the given resource.
+// The log ID gets URL-encoded, e.g. "syslog/app" becomes "syslog%2Fapp".
+// An empty project ID or resource defaults to the ones of the DefaultEnvironment.
+// An error is returned if neither a project ID nor a log ID can be determined.
+func NewCloudLoggingExporter(client *http.Client, projectID, logID string, resource Resource, options CloudLoggingOptions) (*CloudLoggingExporter, error) {
+	if len(projectID) == 0 {
+		projectID = DefaultEnvironment.ProjectID
+	}
+	if len(projectID) == 0 {
+		return nil, errors.New("cannot create Cloud Logging exporter because the project ID is empty")
+	}
+	if len(logID) == 0 {
+		return nil, errors.New("cannot create Cloud Logging exporter because the log ID is empty")
+	}
+	if len(resource.Type) == 0 {
+		resource = DefaultEnvironment.Resource
+	}
+	if len(resource.Type) == 0 {
+		resource.Type = "global"
+	}
+	if len(options.Endpoint) == 0 {
+		options.Endpoint = DefaultCloudLoggingEndpoint
+	}
+
+	e := &CloudLoggingExporter{
+		projectID: projectID,
+		logName:   fmt.Sprintf("projects/%s/logs/%s", projectID, url.PathEscape(logID)),
+		resource:  resource,
+		request:   batch.Request{Client: client, Name: "Cloud Logging", URL: options.Endpoint},
+	}
+	e.batcher = batch.New(
+		batch.Options{
+			BatchSize:     options.BatchSize,
+			MaxBuffered:   options.MaxBufferedEntries,
+			FlushInterval: options.FlushInterval,
+			MaxRetries:    options.MaxRetries,
+			RetryBackoff:  options.RetryBackoff,
+		},
+		e.send,
+		"Failed to write log entries to Google Cloud Logging")
+	return e, nil
+}
+
+// Export adds a log message of an unknown level as a text payload to the current batch.
+func (e *CloudLoggingExporter) Export(output string) {
+	b := append([]byte(nil), `{"severity":"DEFAULT","textPayload":`...)
+	b = appendJSONString(b, output)
+	b = append(b, '}')
+	e.batcher.Add(b)
+}
+
+// ExportEntry adds a log entry to the current batch.
+// The formatted output is ignored, because the entry gets mapped onto
+// the LogEntry schema of the Cloud Logging API.
+func (e *CloudLoggingExporter) ExportEntry(entry Entry, _ string) {
+	e.batcher.Add(e.appendLogEntry(nil, entry))
+}
+
+// Dropped returns the number of log entries which have been discarded due to a full buffer.
+func (e *CloudLoggingExporter) Dropped() uint64 {
+	return e.batcher.Dropped()
+}
+
+// Flush writes all buffered log entries in batches to the Cloud Logging API.
+// Entries of a batch which could not be written after all retries are discarded.
+func (e *CloudLoggingExporter) Flush(ctx context.Context) error {
+	return e.batcher.Flush(ctx)
+}
+
+// Close stops the background flushing and writes all remaining log entries.
+// Log entries which get exported after Close are written immediately.
+func (e *CloudLoggingExporter) Close(ctx context.Context) error {
+	return e.batcher.Close(ctx)
+}
+
+func (e *CloudLoggingExporter) send(ctx context.Context, logEntries []interface{}) error {
+	body := append([]byte(nil), `{"logName":`...)
+	body = appendJSONString(body, e.logName)
+	resource, err := json.Marshal(e.resource)
+	if err != nil {
+		return fmt.Errorf("error serializing monitored resource: %w", err)
+	}
+	body = append(body, `,"resource":`...)
+	body = append(body, resource...)
+	body = append(body, `,"partialSuccess":true,"entries":[`...)
+	for i, logEntry := range logEntries {
+		if i > 0 {
+			body = append(body, ',')
+		}
+		body = append(body, logEntry.([]byte)...)
+	}
+	body = append(body, "]}"...)
+
+	return e.batcher.Post(ctx, e.request, body)
+}
+
+// appendLogEntry maps an entry onto the LogEntry schema of the Cloud Logging API.
+// See more at: https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry
+func (e *CloudLoggingExporter) appendLogEntry(dst []byte, entry Entry) []byte {
+	dst = append(dst, `{"severity":"`...)
+	dst = append(dst, entry.Level.String()...)
+	dst = append(dst, '"')
+
+	if !entry.Timestamp.IsZero() {
+		dst = append(dst, `,"timestamp":"`...)
+		dst = entry.Timestamp.UTC().AppendFormat(dst, time.RFC3339Nano)
+		dst = append(dst, '"')
+	}
+
+	if entry.TraceID.IsValid() {
+		dst = append(dst, `,"trace":`...)
+		dst = appendJSONString(dst, "projects/"+e.projectID+"/traces/"+entry.TraceID.String())
+		dst = append(dst, `,"traceSampled":`...)
+		dst = strconv.AppendBool(dst, entry.TraceSampled)
+
+		if entry.SpanID.IsValid() {
+			dst = append(dst, `,"spanId":"`...)
+			dst = appendHex(dst, entry.SpanID[:])
+			dst = append(dst, '"')
+		}
+	}
+
+	if len(entry.Labels) > 0 {
+		buf := getBuffer()
+		dst = append(dst, `,"labels":{`...)
+		for i, key := range buf.sortedKeys(entry.Labels) {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = appendJSONString(dst, key)
+			dst = append(dst, ':')
+			dst = appendJSONString(dst, entry.Labels[key])
+		}
+		dst = append(dst, '}')
+		putBuffer(buf)
+	}
+
+	if entry.HasHTTPRequest() {
+		dst = append(dst, `,"httpRequest":`...)
+		dst = appendHTTPRequest(dst, entry.HTTPRequest)
+	}
+
+	if entry.SourceLocation != nil {
+		dst = append(dst, `,"sourceLocation":`...)
+		dst = appendSourceLocation(dst, entry.SourceLocation)
+	}
+
+	dst = append(dst, `,"jsonPayload":{`...)
+	dst = appendMessage(dst, entry)
+	dst = appendServiceContext(dst, entry)
+	dst = appendReportLocation(dst, entry)
+	dst = appendErrorChain(dst, entry)
+	dst = appendFieldsAndData(dst, entry, isStackdriverKey)
+	return append(dst, "}}"...)
+}
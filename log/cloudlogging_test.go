@@ -0,0 +1,256 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dusted-go/diagnostic/gcp"
+	"github.com/dusted-go/diagnostic/trace"
+)
+
+type writeRequest struct {
+	LogName  string                   `json:"logName"`
+	Resource Resource                 `json:"resource"`
+	Entries  []map[string]interface{} `json:"entries"`
+}
+
+type fakeCloudLogging struct {
+	mutex    sync.Mutex
+	failures int
+	requests []writeRequest
+}
+
+func (f *fakeCloudLogging) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.failures > 0 {
+		f.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	body, _ := ioutil.ReadAll(r.Body)
+	req := writeRequest{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.requests = append(f.requests, req)
+	_, _ = w.Write([]byte("{}"))
+}
+
+func (f *fakeCloudLogging) result() []writeRequest {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]writeRequest(nil), f.requests...)
+}
+
+func Test_CloudLoggingExporter_Close_WritesLogEntries(t *testing.T) {
+	fake := &fakeCloudLogging{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	exporter, err := NewCloudLoggingExporter(
+		server.Client(),
+		"my-project",
+		"my-log",
+		Resource{Type: "gce_instance", Labels: map[string]string{"instance_id": "123"}},
+		CloudLoggingOptions{Endpoint: server.URL, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	traceID, _ := trace.ParseID("0af7651916cd43dd8448eb211c80319c")
+	spanID, _ := trace.ParseOpenTelemetrySpanID("b7ad6b7169203331")
+	e := New(nil, &Stackdriver{}, exporter, Debug).
+		SetTraceID(traceID).
+		SetSpanID(spanID).
+		SetServiceName("foo-bar").
+		AddLabel("a", "A").
+		Int("status", 200)
+	e.Info().Msg("hello")
+	exporter.Export("plain text")
+
+	if err := exporter.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := fake.result()
+	if len(requests) != 1 {
+		t.Fatalf("Expected 1 request, but got %d.", len(requests))
+	}
+
+	req := requests[0]
+	if req.LogName != "projects/my-project/logs/my-log" || req.Resource.Type != "gce_instance" || req.Resource.Labels["instance_id"] != "123" {
+		t.Errorf("Unexpected log name or resource: %+v", req)
+	}
+	if len(req.Entries) != 2 {
+		t.Fatalf("Expected 2 entries, but got %d.", len(req.Entries))
+	}
+
+	entry := req.Entries[0]
+	payload := entry["jsonPayload"].(map[string]interface{})
+	if entry["severity"] != "INFO" ||
+		entry["trace"] != "projects/my-project/traces/0af7651916cd43dd8448eb211c80319c" ||
+		entry["spanId"] != "b7ad6b7169203331" ||
+		entry["labels"].(map[string]interface{})["a"] != "A" ||
+		payload["message"] != "hello" ||
+		payload["status"] != float64(200) ||
+		payload["serviceContext"].(map[string]interface{})["service"] != "foo-bar" {
+		t.Errorf("Unexpected log entry: %+v", entry)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, entry["timestamp"].(string)); err != nil {
+		t.Errorf("Unexpected timestamp: %v", err)
+	}
+	if req.Entries[1]["textPayload"] != "plain text" {
+		t.Errorf("Unexpected log entry: %+v", req.Entries[1])
+	}
+}
+
+func Test_CloudLoggingExporter_BatchSize_TriggersWriteInBatches(t *testing.T) {
+	fake := &fakeCloudLogging{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	exporter, err := NewCloudLoggingExporter(
+		server.Client(), "p", "l", Resource{},
+		CloudLoggingOptions{Endpoint: server.URL, BatchSize: 2, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5; i++ {
+		exporter.Export("x")
+	}
+	if err := exporter.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	total := 0
+	for _, req := range fake.result() {
+		if len(req.Entries) > 2 {
+			t.Errorf("Expected at most 2 entries per request, but got %d.", len(req.Entries))
+		}
+		if req.Resource.Type != "global" {
+			t.Errorf("Expected default resource type global, but got %s.", req.Resource.Type)
+		}
+		total += len(req.Entries)
+	}
+	if total != 5 {
+		t.Errorf("Expected 5 entries, but got %d.", total)
+	}
+}
+
+func Test_CloudLoggingExporter_DefaultsToDefaultEnvironment(t *testing.T) {
+	defer func(env gcp.Environment) { DefaultEnvironment = env }(DefaultEnvironment)
+	DefaultEnvironment = gcp.Environment{
+		ProjectID: "env-project",
+		Resource:  Resource{Type: "cloud_run_revision", Labels: map[string]string{"service_name": "api"}},
+	}
+
+	fake := &fakeCloudLogging{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	exporter, err := NewCloudLoggingExporter(
+		server.Client(), "", "l", Resource{},
+		CloudLoggingOptions{Endpoint: server.URL, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exporter.Export("x")
+	if err := exporter.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := fake.result()
+	if len(requests) != 1 {
+		t.Fatalf("Expected 1 request, but got %d.", len(requests))
+	}
+	req := requests[0]
+	if req.LogName != "projects/env-project/logs/l" || req.Resource.Type != "cloud_run_revision" || req.Resource.Labels["service_name"] != "api" {
+		t.Errorf("Unexpected log name or resource: %s, %+v", req.LogName, req.Resource)
+	}
+}
+
+func Test_CloudLoggingExporter_Flush_RetriesFailedRequests(t *testing.T) {
+	fake := &fakeCloudLogging{failures: 2}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	exporter, err := NewCloudLoggingExporter(
+		server.Client(), "p", "l", Resource{},
+		CloudLoggingOptions{Endpoint: server.URL, FlushInterval: time.Hour, RetryBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer exporter.Close(context.Background())
+
+	exporter.Export("x")
+	if err := exporter.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.result()) != 1 {
+		t.Errorf("Expected 1 successful request, but got %d.", len(fake.result()))
+	}
+}
+
+func Test_CloudLoggingExporter_Flush_GivesUpAfterMaxRetries(t *testing.T) {
+	fake := &fakeCloudLogging{failures: 10}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	exporter, err := NewCloudLoggingExporter(
+		server.Client(), "p", "l", Resource{},
+		CloudLoggingOptions{Endpoint: server.URL, FlushInterval: time.Hour, MaxRetries: 2, RetryBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer exporter.Close(context.Background())
+
+	exporter.Export("x")
+	if err := exporter.Flush(context.Background()); err == nil {
+		t.Error("Expected an error after all retries failed.")
+	}
+	if fake.failures != 7 {
+		t.Errorf("Expected 3 attempts, but got %d.", 10-fake.failures)
+	}
+}
+
+func Test_NewCloudLoggingExporter_EmptyProjectID_ReturnsError(t *testing.T) {
+	defer func(env gcp.Environment) { DefaultEnvironment = env }(DefaultEnvironment)
+	DefaultEnvironment = gcp.Environment{}
+
+	if _, err := NewCloudLoggingExporter(nil, "", "l", Resource{}, CloudLoggingOptions{}); err == nil {
+		t.Error("Expected an error for an empty project ID.")
+	}
+}
+
+func Test_CloudLoggingExporter_LogID_IsURLEncoded(t *testing.T) {
+	fake := &fakeCloudLogging{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	exporter, err := NewCloudLoggingExporter(
+		server.Client(), "p", "syslog/app", Resource{},
+		CloudLoggingOptions{Endpoint: server.URL, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exporter.Export("x")
+	if err := exporter.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "projects/p/logs/syslog%2Fapp"
+	if requests := fake.result(); len(requests) != 1 || requests[0].LogName != expected {
+		t.Errorf("Expected log name %s, but got: %+v", expected, requests)
+	}
+}
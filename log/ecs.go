@@ -0,0 +1,162 @@
+package log
+
+import (
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ECSVersion is the version of the Elastic Common Schema which the ECS formatter writes.
+const ECSVersion = "1.6.0"
+
+// ECS formats an event into the Elastic Common Schema JSON format.
+// Fields are written as top level JSON properties and the data as "data".
+// Fields which clash with an ECS property are prefixed with "fields.".
+// See more at: https://www.elastic.co/guide/en/ecs/current/ecs-field-reference.html
+type ECS struct {
+}
+
+// isECSKey checks if a key is a property of the ECS format.
+func isECSKey(key string) bool {
+	switch key {
+	case "@timestamp", "message", "labels", "data":
+		return true
+	}
+	for _, prefix := range []string{"ecs.", "log.", "error.", "trace.", "span.", "service.", "http.", "url.", "user_agent.", "client.", "server.", "event."} {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// appendECSNumber appends a decimal string like a request size as a JSON number if it is valid.
+func appendECSNumber(dst []byte, key, value string) []byte {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return dst
+	}
+	dst = appendJSONKey(dst, key)
+	return strconv.AppendInt(dst, n, 10)
+}
+
+func appendECSString(dst []byte, key, value string) []byte {
+	if len(value) == 0 {
+		return dst
+	}
+	dst = appendJSONKey(dst, key)
+	return appendJSONString(dst, value)
+}
+
+// appendECSURL writes the request URL as url.original and its path and query.
+// Server requests usually only carry a path, so url.full is only written for absolute URLs.
+func appendECSURL(dst []byte, requestURL string) []byte {
+	dst = appendECSString(dst, "url.original", requestURL)
+	u, err := url.Parse(requestURL)
+	if err != nil {
+		return dst
+	}
+	if u.IsAbs() && len(u.Host) > 0 {
+		dst = appendECSString(dst, "url.full", requestURL)
+		dst = appendECSString(dst, "url.scheme", u.Scheme)
+		dst = appendECSString(dst, "url.domain", u.Hostname())
+	}
+	dst = appendECSString(dst, "url.path", u.Path)
+	dst = appendECSString(dst, "url.query", u.RawQuery)
+	return dst
+}
+
+func appendECSHTTPRequest(dst []byte, req *HTTPRequest) []byte {
+	dst = appendECSString(dst, "http.request.method", req.RequestMethod)
+	dst = appendECSURL(dst, req.RequestURL)
+	dst = appendECSNumber(dst, "http.request.body.bytes", req.RequestSize)
+	dst = appendECSString(dst, "http.request.referrer", req.Referer)
+	if req.Status != 0 {
+		dst = appendJSONKey(dst, "http.response.status_code")
+		dst = strconv.AppendInt(dst, int64(req.Status), 10)
+	}
+	dst = appendECSNumber(dst, "http.response.body.bytes", req.ResponseSize)
+	dst = appendECSString(dst, "http.version", strings.TrimPrefix(req.Protocol, "HTTP/"))
+	dst = appendECSString(dst, "user_agent.original", req.UserAgent)
+	dst = appendECSString(dst, "client.ip", req.RemoteIP)
+	dst = appendECSString(dst, "server.ip", req.ServerIP)
+	if latency, err := time.ParseDuration(req.Latency); err == nil {
+		dst = appendJSONKey(dst, "event.duration")
+		dst = strconv.AppendInt(dst, latency.Nanoseconds(), 10)
+	}
+	return dst
+}
+
+// Format formats a log entry into an ECS JSON object.
+func (f *ECS) Format(e Entry) string {
+	buf := getBuffer()
+	b := buf.bytes
+
+	b = append(b, '{')
+	if !e.Timestamp.IsZero() {
+		b = append(b, `"@timestamp":"`...)
+		b = e.Timestamp.UTC().AppendFormat(b, time.RFC3339Nano)
+		b = append(b, `",`...)
+	}
+	b = append(b, `"log.level":`...)
+	b = appendJSONString(b, strings.ToLower(e.Level.String()))
+	b = append(b, `,"message":`...)
+	b = appendJSONString(b, e.Message)
+	b = append(b, `,"ecs.version":"`+ECSVersion+`"`...)
+
+	if e.Error != nil {
+		b = append(b, `,"error.message":`...)
+		b = appendJSONString(b, e.Error.Error())
+		b = append(b, `,"error.type":`...)
+		b = appendJSONString(b, reflect.TypeOf(e.Error).String())
+		b = appendECSString(b, "error.stack_trace", e.StackTrace())
+	}
+
+	if e.TraceID.IsValid() {
+		b = append(b, `,"trace.id":"`...)
+		b = appendHex(b, e.TraceID[:])
+		b = append(b, '"')
+		if e.SpanID.IsValid() {
+			b = append(b, `,"span.id":"`...)
+			b = appendHex(b, e.SpanID[:])
+			b = append(b, '"')
+		}
+	}
+
+	b = appendECSString(b, "service.name", e.ServiceName)
+	b = appendECSString(b, "service.version", e.ServiceVersion)
+
+	if e.SourceLocation != nil {
+		b = appendECSString(b, "log.origin.file.name", e.SourceLocation.File)
+		b = append(b, `,"log.origin.file.line":`...)
+		b = strconv.AppendInt(b, int64(e.SourceLocation.Line), 10)
+		b = appendECSString(b, "log.origin.function", e.SourceLocation.Function)
+	}
+
+	if len(e.Labels) > 0 {
+		b = append(b, `,"labels":{`...)
+		for i, key := range buf.sortedKeys(e.Labels) {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = appendJSONString(b, key)
+			b = append(b, ':')
+			b = appendJSONString(b, e.Labels[key])
+		}
+		b = append(b, '}')
+	}
+
+	if e.HasHTTPRequest() {
+		b = appendECSHTTPRequest(b, e.HTTPRequest)
+	}
+
+	b = appendFieldsAndData(b, e, isECSKey)
+	b = append(b, '}')
+	output := string(b)
+
+	buf.bytes = b
+	putBuffer(buf)
+	return output
+}
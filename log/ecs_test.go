@@ -0,0 +1,96 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dusted-go/diagnostic/trace"
+)
+
+func Test_ECS_FormatsCorrectly(t *testing.T) {
+	ecs := ECS{}
+
+	sc, err := trace.ParseTraceparent("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &http.Request{
+		Method:        "POST",
+		Host:          "example.org",
+		RequestURI:    "/orders",
+		ContentLength: 42,
+		Header:        map[string][]string{"User-Agent": {"curl/7.64.1"}},
+		RemoteAddr:    "10.0.0.1:4321",
+		Proto:         "HTTP/1.1",
+	}
+
+	e := event{level: Error, message: "order failed"}.
+		SetSpanContext(sc).
+		SetServiceName("shop").
+		SetServiceVersion("1.4.0").
+		AddLabel("team", "checkout").
+		SetHTTPRequest(req).
+		SetHTTPResponse(500, 12, 1500*time.Millisecond).
+		SetError(errors.New("out of stock")).
+		Int("order_id", 7).(event).
+		entry(time.Date(2021, 6, 1, 10, 30, 0, 0, time.UTC))
+
+	expected := "{\"@timestamp\":\"2021-06-01T10:30:00Z\",\"log.level\":\"error\",\"message\":\"order failed\",\"ecs.version\":\"1.6.0\",\"error.message\":\"out of stock\",\"error.type\":\"*errors.errorString\",\"trace.id\":\"0af7651916cd43dd8448eb211c80319c\",\"span.id\":\"b7ad6b7169203331\",\"service.name\":\"shop\",\"service.version\":\"1.4.0\",\"labels\":{\"team\":\"checkout\"},\"http.request.method\":\"POST\",\"url.original\":\"example.org/orders\",\"url.path\":\"example.org/orders\",\"http.request.body.bytes\":42,\"http.response.status_code\":500,\"http.response.body.bytes\":12,\"http.version\":\"1.1\",\"user_agent.original\":\"curl/7.64.1\",\"client.ip\":\"10.0.0.1\",\"event.duration\":1500000000,\"order_id\":7}"
+	if actual := ecs.Format(e); actual != expected {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
+	}
+}
+
+func Test_ECS_WithStackTrace_WritesStackTrace(t *testing.T) {
+	filter := &recordingFilter{}
+	New(filter, &ECS{}, &recordingExporter{}, Debug).Error().SetError(errors.New("boom")).Msg("failed")
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte((&ECS{}).Format(filter.entries[0])), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if stack, _ := doc["error.stack_trace"].(string); !strings.HasPrefix(stack, "goroutine 1 [running]:\n") {
+		t.Errorf("Expected a stack trace, but got %q", stack)
+	}
+}
+
+func Test_ECS_FieldsWithReservedKeys_DoNotOverrideEntry(t *testing.T) {
+	e := event{level: Info, message: "real"}.
+		Str("log.level", "debug").
+		Str("message", "fake").(event).
+		entry(time.Date(2021, 6, 1, 10, 30, 0, 0, time.UTC))
+
+	expected := "{\"@timestamp\":\"2021-06-01T10:30:00Z\",\"log.level\":\"info\",\"message\":\"real\",\"ecs.version\":\"1.6.0\",\"fields.log.level\":\"debug\",\"fields.message\":\"fake\"}"
+	if actual := (&ECS{}).Format(e); actual != expected {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
+	}
+}
+
+func Test_ECS_ZeroTimestamp_IsOmitted(t *testing.T) {
+	e := event{level: Info, message: "hello"}.entry(time.Time{})
+
+	expected := "{\"log.level\":\"info\",\"message\":\"hello\",\"ecs.version\":\"1.6.0\"}"
+	if actual := (&ECS{}).Format(e); actual != expected {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
+	}
+}
+
+func Test_AppendECSURL_OnlyWritesFullURLIfAbsolute(t *testing.T) {
+	testCases := []struct {
+		URL      string
+		Expected string
+	}{
+		{"/orders?id=7", ",\"url.original\":\"/orders?id=7\",\"url.path\":\"/orders\",\"url.query\":\"id=7\""},
+		{"https://example.org/orders", ",\"url.original\":\"https://example.org/orders\",\"url.full\":\"https://example.org/orders\",\"url.scheme\":\"https\",\"url.domain\":\"example.org\",\"url.path\":\"/orders\""},
+	}
+
+	for _, testCase := range testCases {
+		if actual := string(appendECSURL(nil, testCase.URL)); actual != testCase.Expected {
+			t.Errorf("\nExpected:\n%s,\nActual:\n%s", testCase.Expected, actual)
+		}
+	}
+}
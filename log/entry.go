@@ -0,0 +1,120 @@
+package log
+
+import (
+	"time"
+
+	"github.com/dusted-go/diagnostic/trace"
+)
+
+// HTTPRequest holds the details of a HTTP request which is associated with a log event.
+// See more at: https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#HttpRequest
+type HTTPRequest struct {
+	RequestMethod                  string `json:"requestMethod"`
+	RequestURL                     string `json:"requestUrl"`
+	RequestSize                    string `json:"requestSize"`
+	Status                         int    `json:"status,omitempty"`
+	ResponseSize                   string `json:"responseSize,omitempty"`
+	UserAgent                      string `json:"userAgent"`
+	RemoteIP                       string `json:"remoteIp"`
+	ServerIP                       string `json:"serverIp"`
+	Referer                        string `json:"referer"`
+	Latency                        string `json:"latency,omitempty"`
+	CacheLookup                    bool   `json:"cacheLookup,omitempty"`
+	CacheHit                       bool   `json:"cacheHit,omitempty"`
+	CacheValidatedWithOriginServer bool   `json:"cacheValidatedWithOriginServer,omitempty"`
+	CacheFillBytes                 string `json:"cacheFillBytes,omitempty"`
+	Protocol                       string `json:"protocol"`
+}
+
+// ReportLocation is the location in the source code where an error has been logged.
+// See more at: https://cloud.google.com/error-reporting/reference/rest/v1beta1/ErrorContext#SourceLocation
+type ReportLocation struct {
+	FilePath     string
+	LineNumber   int
+	FunctionName string
+}
+
+// SourceLocation is the location in the source code where a log entry has been written.
+// See more at: https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#LogEntrySourceLocation
+type SourceLocation struct {
+	File     string
+	Line     int
+	Function string
+}
+
+// Entry is a read-only view of a log event at the time it gets emitted.
+// Formatters and filters receive an Entry, which makes it possible to
+// implement them outside of this package.
+// Fields are shared with the originating event and must not be modified.
+type Entry struct {
+	Timestamp      time.Time
+	Level          Level
+	Message        string
+	Error          error
+	Data           interface{}
+	Labels         map[string]string
+	Fields         []Field
+	TraceID        trace.ID
+	SpanID         trace.SpanID
+	TraceSampled   bool
+	ServiceName    string
+	ServiceVersion string
+	ProjectID      string
+	HTTPRequest    *HTTPRequest
+	ReportLocation *ReportLocation
+	SourceLocation *SourceLocation
+	Stack          []uintptr
+}
+
+// HasHTTPRequest returns true if the entry has an associated HTTP request.
+func (e Entry) HasHTTPRequest() bool {
+	return e.HTTPRequest != nil
+}
+
+// StackTrace returns the stack trace of the entry's error in the layout of a Go panic.
+// It returns an empty string if the entry has no stack trace.
+func (e Entry) StackTrace() string {
+	return formatStack(e.Stack)
+}
+
+func (e event) entry(timestamp time.Time) Entry {
+	var labels map[string]string
+	if len(e.labels) > 0 {
+		labels = make(map[string]string, len(e.labels))
+		for k, v := range e.labels {
+			labels[k] = v
+		}
+	}
+
+	var req *HTTPRequest
+	if e.hasHTTPRequest {
+		r := e.httpRequest
+		if e.trustedProxies > 0 {
+			value := e.xForwardedFor
+			if e.proxyHeader == ForwardedHeader {
+				value = e.forwarded
+			}
+			if ip := forwardedFor(e.proxyHeader, value, e.trustedProxies); len(ip) > 0 {
+				r.RemoteIP = ip
+			}
+		}
+		req = &r
+	}
+
+	return Entry{
+		Timestamp:      timestamp,
+		Level:          e.level,
+		Message:        e.message,
+		Error:          e.err,
+		Data:           e.data,
+		Labels:         labels,
+		Fields:         e.fields,
+		TraceID:        e.traceID,
+		SpanID:         e.spanID,
+		TraceSampled:   e.traceSampled || !e.hasSampled,
+		ServiceName:    e.serviceName,
+		ServiceVersion: e.serviceVersion,
+		ProjectID:      e.projectID,
+		HTTPRequest:    req,
+	}
+}
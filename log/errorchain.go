@@ -0,0 +1,53 @@
+package log
+
+import "reflect"
+
+// ErrorWithFields can be implemented by errors which carry structured information about
+// a failure, for example the status code of a failed downstream call.
+type ErrorWithFields interface {
+	error
+	ErrorFields() map[string]interface{}
+}
+
+// Cause is a single error of an error chain.
+type Cause struct {
+	Message string
+	Type    string
+	Fields  map[string]interface{}
+}
+
+const maxErrorChainLength = 32
+
+// ErrorChain returns the entry's error and all errors which it wraps in depth-first order.
+// Errors which wrap multiple errors with an Unwrap() []error method are supported as well.
+func (e Entry) ErrorChain() []Cause {
+	return errorChain(e.Error)
+}
+
+func errorChain(err error) []Cause {
+	var chain []Cause
+	var walk func(error)
+	walk = func(err error) {
+		for err != nil && len(chain) < maxErrorChainLength {
+			cause := Cause{Message: err.Error(), Type: reflect.TypeOf(err).String()}
+			if f, ok := err.(ErrorWithFields); ok {
+				cause.Fields = f.ErrorFields()
+			}
+			chain = append(chain, cause)
+
+			switch wrapper := err.(type) {
+			case interface{ Unwrap() []error }:
+				for _, inner := range wrapper.Unwrap() {
+					walk(inner)
+				}
+				return
+			case interface{ Unwrap() error }:
+				err = wrapper.Unwrap()
+			default:
+				return
+			}
+		}
+	}
+	walk(err)
+	return chain
+}
@@ -0,0 +1,70 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type downstreamError struct {
+	status int
+}
+
+func (e *downstreamError) Error() string {
+	return fmt.Sprintf("downstream responded with %d", e.status)
+}
+
+func (e *downstreamError) ErrorFields() map[string]interface{} {
+	return map[string]interface{}{"status": e.status, "service": "billing"}
+}
+
+type multiError []error
+
+func (m multiError) Error() string {
+	return fmt.Sprintf("%d errors", len(m))
+}
+
+func (m multiError) Unwrap() []error {
+	return m
+}
+
+func Test_ErrorChain_WalksWrappedAndMultiErrors(t *testing.T) {
+	root := &downstreamError{status: 503}
+	err := fmt.Errorf("charging failed: %w", multiError{root, errors.New("timeout")})
+
+	expected := []Cause{
+		{Message: "charging failed: 2 errors", Type: "*fmt.wrapError"},
+		{Message: "2 errors", Type: "log.multiError"},
+		{Message: "downstream responded with 503", Type: "*log.downstreamError", Fields: map[string]interface{}{"status": 503, "service": "billing"}},
+		{Message: "timeout", Type: "*errors.errorString"},
+	}
+	if actual := (Entry{Error: err}).ErrorChain(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("\nExpected:\n%+v,\nActual:\n%+v", expected, actual)
+	}
+
+	if chain := (Entry{}).ErrorChain(); chain != nil {
+		t.Errorf("Expected no error chain, but got %+v", chain)
+	}
+}
+
+func Test_Stackdriver_Error_WritesErrorChain(t *testing.T) {
+	err := fmt.Errorf("charging failed: %w", &downstreamError{status: 503})
+
+	output := (&Stackdriver{}).Format(Entry{Level: Error, Error: err})
+	expected := ",\"errorChain\":[{\"message\":\"charging failed: downstream responded with 503\",\"type\":\"*fmt.wrapError\"},{\"message\":\"downstream responded with 503\",\"type\":\"*log.downstreamError\",\"fields\":{\"service\":\"billing\",\"status\":503}}]}"
+	if !strings.HasSuffix(output, expected) {
+		t.Errorf("\nExpected suffix:\n%s,\nActual:\n%s", expected, output)
+	}
+}
+
+func Test_Console_Error_WritesCauses(t *testing.T) {
+	err := fmt.Errorf("charging failed: %w", &downstreamError{status: 503})
+
+	output := (&Console{}).Format(Entry{Level: Error, Error: err})
+	expected := "charging failed: downstream responded with 503\n  caused by *log.downstreamError: downstream responded with 503 service=billing status=503"
+	if !strings.Contains(output, expected) {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, output)
+	}
+}
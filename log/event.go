@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/dusted-go/diagnostic/gcp"
 	"github.com/dusted-go/diagnostic/trace"
 )
 
@@ -15,13 +18,33 @@ type Event interface {
 	SetMinLogLevel(Level) Event
 	SetServiceName(string) Event
 	SetServiceVersion(string) Event
+	SetEnvironment(gcp.Environment) Event
+	SetTrustedProxies(int, string) Event
+	SetCallerSkip(int) Event
+	SetSourceLocation(bool) Event
+	SetTime(time.Time) Event
+	SetClock(func() time.Time) Event
 	SetHTTPRequest(*http.Request) Event
+	SetHTTPResponse(int, int64, time.Duration) Event
+	SetHTTPCache(bool, bool, bool, int64) Event
 	SetError(error) Event
 	SetData(interface{}) Event
 	SetTraceID(trace.ID) Event
 	SetSpanID(trace.SpanID) Event
+	SetTraceSampled(bool) Event
+	SetSpanContext(trace.SpanContext) Event
 	AddLabel(string, string) Event
 
+	Str(string, string) Event
+	Int(string, int) Event
+	Int64(string, int64) Event
+	Float(string, float64) Event
+	Bool(string, bool) Event
+	Dur(string, time.Duration) Event
+	Time(string, time.Time) Event
+	Any(string, interface{}) Event
+	Err(string, error) Event
+
 	Debug() Event
 	Info() Event
 	Notice() Event
@@ -44,17 +67,6 @@ type serviceContext struct {
 	Version string
 }
 
-type httpRequest struct {
-	RequestMethod string `json:"requestMethod"`
-	RequestURL    string `json:"requestUrl"`
-	RequestSize   string `json:"requestSize"`
-	UserAgent     string `json:"userAgent"`
-	RemoteIP      string `json:"remoteIp"`
-	ServerIP      string `json:"serverIp"`
-	Referer       string `json:"referer"`
-	Protocol      string `json:"protocol"`
-}
-
 type event struct {
 	filter         Filter
 	formatter      Formatter
@@ -63,13 +75,25 @@ type event struct {
 	level          Level
 	serviceName    string
 	serviceVersion string
-	httpRequest    httpRequest
+	projectID      string
+	trustedProxies int
+	proxyHeader    string
+	forwarded      string
+	xForwardedFor  string
+	callerSkip     int
+	sourceLocation bool
+	timestamp      time.Time
+	clock          func() time.Time
+	httpRequest    HTTPRequest
 	hasHTTPRequest bool
 	err            error
 	data           interface{}
 	labels         map[string]string
+	fields         []Field
 	traceID        trace.ID
 	spanID         trace.SpanID
+	traceSampled   bool
+	hasSampled     bool
 	message        string
 }
 
@@ -112,6 +136,68 @@ func (e event) SetServiceVersion(version string) Event {
 	return e
 }
 
+// SetEnvironment sets the service name, service version and project ID of a detected environment.
+// Empty values of the environment do not overwrite existing values.
+func (e event) SetEnvironment(env gcp.Environment) Event {
+	if len(env.ServiceName) > 0 {
+		e.serviceName = env.ServiceName
+	}
+	if len(env.ServiceVersion) > 0 {
+		e.serviceVersion = env.ServiceVersion
+	}
+	if len(env.ProjectID) > 0 {
+		e.projectID = env.ProjectID
+	}
+	return e
+}
+
+// SetCallerSkip sets the number of additional stack frames which get skipped when the
+// source location and the report location and stack trace of an error get captured. Helper functions which
+// wrap the emitting of log events should increase it by one for every level of wrapping.
+func (e event) SetCallerSkip(skip int) Event {
+	if skip < 0 {
+		skip = 0
+	}
+	e.callerSkip = skip
+	return e
+}
+
+// SetSourceLocation decides if the file, line and function which emit the log event get captured.
+// It is disabled by default, because it costs a stack walk on every emitted log event.
+func (e event) SetSourceLocation(enabled bool) Event {
+	e.sourceLocation = enabled
+	return e
+}
+
+// SetTime overrides the timestamp of the log event, e.g. when replaying historical records.
+// A zero time resets the timestamp to the time when the log event gets emitted.
+func (e event) SetTime(timestamp time.Time) Event {
+	e.timestamp = timestamp
+	return e
+}
+
+// SetClock sets the function which returns the current time when the log event gets emitted.
+// A nil clock defaults to time.Now.
+func (e event) SetClock(clock func() time.Time) Event {
+	e.clock = clock
+	return e
+}
+
+// SetTrustedProxies sets the number of reverse proxies in front of the service and the header
+// which they append the address of their client to, ForwardedHeader or XForwardedForHeader.
+// The remote IP of the HTTP request is taken from that header only by counting as many
+// addresses from the right, because clients can prepend any value to it or send other headers.
+// If the header has fewer addresses than trusted proxies, the address of the connection is kept.
+// Zero, the default, ignores the headers and uses the address of the connection.
+func (e event) SetTrustedProxies(count int, header string) Event {
+	if count < 0 {
+		count = 0
+	}
+	e.trustedProxies = count
+	e.proxyHeader = http.CanonicalHeaderKey(header)
+	return e
+}
+
 func (e event) SetHTTPRequest(req *http.Request) Event {
 	if req == nil {
 		return e
@@ -122,15 +208,40 @@ func (e event) SetHTTPRequest(req *http.Request) Event {
 		reqURL = req.URL.String()
 	}
 
-	e.httpRequest = httpRequest{
+	e.httpRequest = HTTPRequest{
 		RequestMethod: req.Method,
 		RequestURL:    reqURL,
 		RequestSize:   strconv.FormatInt(req.ContentLength, 10),
 		UserAgent:     req.UserAgent(),
-		RemoteIP:      req.RemoteAddr,
+		RemoteIP:      stripPort(req.RemoteAddr),
+		ServerIP:      serverIP(req),
 		Referer:       req.Referer(),
 		Protocol:      req.Proto,
-		// ToDo: ServerIP
+	}
+	e.forwarded = strings.Join(req.Header.Values(ForwardedHeader), ",")
+	e.xForwardedFor = strings.Join(req.Header.Values(XForwardedForHeader), ",")
+	e.hasHTTPRequest = true
+	return e
+}
+
+// SetHTTPResponse sets the response status, response size and latency of the HTTP request.
+func (e event) SetHTTPResponse(status int, size int64, latency time.Duration) Event {
+	e.httpRequest.Status = status
+	e.httpRequest.ResponseSize = strconv.FormatInt(size, 10)
+	e.httpRequest.Latency = formatLatency(latency)
+	e.hasHTTPRequest = true
+	return e
+}
+
+// SetHTTPCache sets if a cache lookup was attempted, if it was a cache hit, if the response
+// was validated with the origin server and how many bytes were inserted into the cache.
+func (e event) SetHTTPCache(lookup, hit, validatedWithOriginServer bool, fillBytes int64) Event {
+	e.httpRequest.CacheLookup = lookup
+	e.httpRequest.CacheHit = hit
+	e.httpRequest.CacheValidatedWithOriginServer = validatedWithOriginServer
+	e.httpRequest.CacheFillBytes = ""
+	if fillBytes > 0 {
+		e.httpRequest.CacheFillBytes = strconv.FormatInt(fillBytes, 10)
 	}
 	e.hasHTTPRequest = true
 	return e
@@ -156,6 +267,23 @@ func (e event) SetSpanID(spanID trace.SpanID) Event {
 	return e
 }
 
+// SetTraceSampled sets the sampling decision of the trace.
+// Traces without a sampling decision are marked as sampled.
+func (e event) SetTraceSampled(sampled bool) Event {
+	e.traceSampled = sampled
+	e.hasSampled = true
+	return e
+}
+
+// SetSpanContext sets the trace ID, span ID and sampling decision of a span context.
+func (e event) SetSpanContext(sc trace.SpanContext) Event {
+	e.traceID = sc.TraceID
+	e.spanID = sc.SpanID
+	e.traceSampled = sc.IsSampled()
+	e.hasSampled = true
+	return e
+}
+
 func (e event) AddLabel(key, value string) Event {
 	if e.labels == nil {
 		e.labels = make(map[string]string)
@@ -209,20 +337,54 @@ func (e event) Emergency() Event {
 	return e.setLevel(Emergency)
 }
 
+// emit must be called directly by the exported method which the caller of this package invoked.
+// The stack trace of an error gets captured here, because the formatter might run on another goroutine.
+func (e event) emit(message string) {
+	e.message = message
+	timestamp := e.timestamp
+	if timestamp.IsZero() {
+		if e.clock != nil {
+			timestamp = e.clock()
+		} else {
+			timestamp = time.Now()
+		}
+	}
+	entry := e.entry(timestamp.UTC())
+	var location *SourceLocation
+	if e.sourceLocation || e.err != nil {
+		location = callerLocation(2 + e.callerSkip)
+	}
+	if e.sourceLocation {
+		entry.SourceLocation = location
+	}
+	if e.err != nil {
+		if location != nil {
+			entry.ReportLocation = &ReportLocation{
+				FilePath:     location.File,
+				LineNumber:   location.Line,
+				FunctionName: location.Function,
+			}
+		}
+		entry.Stack = errorStack(e.err)
+		if len(entry.Stack) == 0 {
+			entry.Stack = callers(2 + e.callerSkip)
+		}
+	}
+	if e.filter.CanWrite(entry) {
+		export(e.exporter, entry, e.formatter.Format(entry))
+	}
+}
+
 // Msg emits a log event message.
 func (e event) Msg(message string) {
-	if e.level >= e.minLevel && e.filter.CanWrite(e.message) {
-		e.message = message
-		e.exporter.Export(e.formatter.Format(e))
+	if e.level >= e.minLevel {
+		e.emit(message)
 	}
 }
 
 // Fmt emits a formatted log event message.
 func (e event) Fmt(format string, args ...interface{}) {
 	if e.level >= e.minLevel {
-		e.message = fmt.Sprintf(format, args...)
-		if e.filter.CanWrite(e.message) {
-			e.exporter.Export(e.formatter.Format(e))
-		}
+		e.emit(fmt.Sprintf(format, args...))
 	}
 }
@@ -1,8 +1,14 @@
 package log
 
 import (
+	"errors"
 	"net/http"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/dusted-go/diagnostic/gcp"
 )
 
 func Test_Event_ImmutabilityTest(t *testing.T) {
@@ -49,3 +55,143 @@ func Test_Event_ImmutabilityTest(t *testing.T) {
 		t.Error("Log event has been illegally mutated.")
 	}
 }
+
+type recordingFilter struct {
+	entries []Entry
+}
+
+func (f *recordingFilter) CanWrite(e Entry) bool {
+	f.entries = append(f.entries, e)
+	return e.Level >= Warning
+}
+
+type recordingExporter struct {
+	outputs []string
+}
+
+func (e *recordingExporter) Export(output string) {
+	e.outputs = append(e.outputs, output)
+}
+
+func Test_Event_FilterReceivesEntry(t *testing.T) {
+	filter := &recordingFilter{}
+	exporter := &recordingExporter{}
+	clock := func() time.Time { return time.Date(2021, 6, 1, 12, 30, 0, 500, time.FixedZone("CEST", 2*60*60)) }
+	e := New(filter, &Stackdriver{}, exporter, Debug).SetClock(clock).AddLabel("a", "A")
+
+	e.Info().Msg("dropped")
+	e.Warning().Fmt("kept %d", 1)
+
+	if len(filter.entries) != 2 {
+		t.Fatalf("Expected filter to be called 2 times, but was called %d times.", len(filter.entries))
+	}
+	if filter.entries[0].Message != "dropped" || filter.entries[1].Message != "kept 1" {
+		t.Errorf("Filter received unexpected messages: %q, %q", filter.entries[0].Message, filter.entries[1].Message)
+	}
+	if filter.entries[1].Labels["a"] != "A" || filter.entries[1].Timestamp.IsZero() {
+		t.Error("Filter received an incomplete entry.")
+	}
+
+	expected := "{\"severity\":\"WARNING\",\"timestamp\":\"2021-06-01T10:30:00.0000005Z\",\"message\":\"kept 1\",\"logging.googleapis.com/labels\":{\"a\":\"A\"}}"
+	if len(exporter.outputs) != 1 || exporter.outputs[0] != expected {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%v", expected, exporter.outputs)
+	}
+}
+
+func Test_Event_Fields_AreImmutable(t *testing.T) {
+	base := event{}.Str("a", "A")
+	event1 := base.Int("b", 1)
+	event2 := base.Int("c", 2)
+
+	fields1 := event1.(event).fields
+	fields2 := event2.(event).fields
+
+	if len(base.(event).fields) != 1 || len(fields1) != 2 || len(fields2) != 2 {
+		t.Fatal("Log event has been illegally mutated.")
+	}
+	if fields1[1].Key != "b" || fields2[1].Key != "c" {
+		t.Error("Log event has been illegally mutated.")
+	}
+}
+
+func Test_Event_SetEnvironment_KeepsExistingValues(t *testing.T) {
+	e := event{}.
+		SetServiceName("manual").
+		SetServiceVersion("v1").
+		SetEnvironment(gcp.Environment{ServiceVersion: "rev-2", ProjectID: "p"}).(event)
+
+	if e.serviceName != "manual" || e.serviceVersion != "rev-2" || e.projectID != "p" {
+		t.Errorf("Unexpected service context: %q, %q, %q", e.serviceName, e.serviceVersion, e.projectID)
+	}
+}
+
+func Test_New_AppliesDefaultEnvironment(t *testing.T) {
+	defer func(env gcp.Environment) { DefaultEnvironment = env }(DefaultEnvironment)
+	DefaultEnvironment = gcp.Environment{ServiceName: "svc", ServiceVersion: "rev-1", ProjectID: "p"}
+
+	entry := New(nil, nil, nil, Debug).(event).entry(time.Time{})
+	if entry.ServiceName != "svc" || entry.ServiceVersion != "rev-1" || entry.ProjectID != "p" {
+		t.Errorf("Default environment has not been applied: %+v", entry)
+	}
+}
+
+func Test_Event_Error_CapturesReportLocationOfCaller(t *testing.T) {
+	filter := &recordingFilter{}
+	e := New(filter, &Stackdriver{}, &recordingExporter{}, Debug)
+
+	e.Info().Msg("no error")
+	_, _, line, _ := runtime.Caller(0)
+	e.Error().SetError(errors.New("boom")).Msg("failed")
+
+	if len(filter.entries) != 2 {
+		t.Fatalf("Expected 2 entries, but got %d.", len(filter.entries))
+	}
+	if filter.entries[0].ReportLocation != nil {
+		t.Error("Expected no report location for an entry without an error.")
+	}
+
+	location := filter.entries[1].ReportLocation
+	if location == nil {
+		t.Fatal("Expected a report location for an entry with an error.")
+	}
+	if !strings.HasSuffix(location.FilePath, "event_test.go") ||
+		location.LineNumber != line+1 ||
+		!strings.HasSuffix(location.FunctionName, "Test_Event_Error_CapturesReportLocationOfCaller") {
+		t.Errorf("Unexpected report location: %+v", location)
+	}
+}
+
+func Test_Event_SourceLocation_IsCapturedWhenEnabled(t *testing.T) {
+	filter := &recordingFilter{}
+	e := New(filter, &Stackdriver{}, &recordingExporter{}, Debug)
+
+	e.Info().Msg("disabled")
+	_, file, line, _ := runtime.Caller(0)
+	e.SetSourceLocation(true).Info().Fmt("enabled %d", 1)
+
+	if filter.entries[0].SourceLocation != nil {
+		t.Error("Expected no source location by default.")
+	}
+	location := filter.entries[1].SourceLocation
+	if location == nil || location.File != file || location.Line != line+1 ||
+		!strings.HasSuffix(location.Function, ".Test_Event_SourceLocation_IsCapturedWhenEnabled") {
+		t.Errorf("Unexpected source location: %+v", location)
+	}
+}
+
+func Test_Event_SetTime_OverridesClock(t *testing.T) {
+	filter := &recordingFilter{}
+	historical := time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)
+	e := New(filter, nil, &recordingExporter{}, Debug).
+		SetClock(func() time.Time { return time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC) })
+
+	e.SetTime(historical).Info().Msg("replayed")
+	e.SetTime(historical).SetTime(time.Time{}).Info().Msg("reset")
+
+	if !filter.entries[0].Timestamp.Equal(historical) {
+		t.Errorf("Expected timestamp %s, but got %s", historical, filter.entries[0].Timestamp)
+	}
+	if filter.entries[1].Timestamp.Year() != 2021 {
+		t.Errorf("Expected the timestamp of the clock, but got %s", filter.entries[1].Timestamp)
+	}
+}
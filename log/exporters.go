@@ -1,12 +1,40 @@
 package log
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 // Exporter emits log messages to an output source.
 type Exporter interface {
 	Export(string)
 }
 
+// EntryExporter is an optional interface for exporters which need to know
+// more about a log message than its formatted output (e.g. the log level).
+// If an exporter implements EntryExporter then ExportEntry gets called instead of Export.
+type EntryExporter interface {
+	ExportEntry(Entry, string)
+}
+
+// Flusher is an optional interface for exporters which buffer log messages.
+type Flusher interface {
+	Flush(context.Context) error
+}
+
+// Closer is an optional interface for exporters which need to release resources on shutdown.
+type Closer interface {
+	Close(context.Context) error
+}
+
+func export(exporter Exporter, entry Entry, output string) {
+	if ee, ok := exporter.(EntryExporter); ok {
+		ee.ExportEntry(entry, output)
+		return
+	}
+	exporter.Export(output)
+}
+
 // StdoutExporter emits log events to stdout.
 type StdoutExporter struct{}
 
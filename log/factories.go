@@ -1,6 +1,9 @@
 package log
 
-import "github.com/dusted-go/diagnostic/trace"
+import (
+	"github.com/dusted-go/diagnostic/gcp"
+	"github.com/dusted-go/diagnostic/trace"
+)
 
 // New creates a new default log event.
 func New(filter Filter, formatter Formatter, exporter Exporter, minLevel Level) Event {
@@ -23,10 +26,11 @@ func New(filter Filter, formatter Formatter, exporter Exporter, minLevel Level)
 		minLevel:       minLevel,
 		level:          Debug,
 		hasHTTPRequest: false,
-	}
+	}.SetEnvironment(DefaultEnvironment)
 }
 
 // NewWithTrace creates a new default log event with initialised trace IDs.
+// The trace.DefaultSampler decides if the new trace gets sampled.
 func NewWithTrace(filter Filter, formatter Formatter, exporter Exporter, minLevel Level) Event {
 	if filter == nil {
 		filter = &NoFilter{}
@@ -40,7 +44,7 @@ func NewWithTrace(filter Filter, formatter Formatter, exporter Exporter, minLeve
 		exporter = &StdoutExporter{}
 	}
 
-	traceID, spanID := trace.DefaultGenerator.NewTraceIDs()
+	sc := trace.NewSpanContext(trace.SpanContext{}, "")
 	return event{
 		filter:         filter,
 		formatter:      formatter,
@@ -48,12 +52,19 @@ func NewWithTrace(filter Filter, formatter Formatter, exporter Exporter, minLeve
 		minLevel:       minLevel,
 		level:          Debug,
 		hasHTTPRequest: false,
-		traceID:        traceID,
-		spanID:         spanID,
-	}
+		traceID:        sc.TraceID,
+		spanID:         sc.SpanID,
+		traceSampled:   sc.IsSampled(),
+		hasSampled:     true,
+	}.SetEnvironment(DefaultEnvironment)
 }
 
 var (
+	// DefaultEnvironment gets applied to every event which is created by New or NewWithTrace.
+	// It should be set once at application start up, e.g. with the result of gcp.Detect.
+	// Events which already exist, such as DefaultEvent, must be updated with SetEnvironment.
+	DefaultEnvironment gcp.Environment
+
 	// DefaultEvent returns a default log event.
 	DefaultEvent = New(&NoFilter{}, &Console{}, &StdoutExporter{}, Debug)
 )
@@ -0,0 +1,132 @@
+package log
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// FieldKind denotes the type of value which is stored in a field.
+type FieldKind int
+
+const (
+	// StringField holds a string value in Field.Str.
+	StringField FieldKind = iota
+	// IntField holds an integer value in Field.Int.
+	IntField
+	// FloatField holds a floating point value in Field.Float.
+	FloatField
+	// BoolField holds a boolean value in Field.Int (1 for true, 0 for false).
+	BoolField
+	// DurationField holds a time.Duration value in Field.Int.
+	DurationField
+	// TimeField holds a time.Time value in Field.Time.
+	TimeField
+	// AnyField holds an arbitrary value in Field.Any.
+	AnyField
+	// ErrorField holds an error value in Field.Any.
+	ErrorField
+)
+
+// Field is a typed key/value pair which is attached to a log event.
+type Field struct {
+	Key   string
+	Kind  FieldKind
+	Str   string
+	Int   int64
+	Float float64
+	Time  time.Time
+	Any   interface{}
+}
+
+// Value returns the value of the field as its original Go type.
+func (f Field) Value() interface{} {
+	switch f.Kind {
+	case StringField:
+		return f.Str
+	case IntField:
+		return f.Int
+	case FloatField:
+		return f.Float
+	case BoolField:
+		return f.Int == 1
+	case DurationField:
+		return time.Duration(f.Int)
+	case TimeField:
+		return f.Time
+	default:
+		return f.Any
+	}
+}
+
+// String returns a human readable representation of the field's value.
+func (f Field) String() string {
+	switch f.Kind {
+	case StringField:
+		return f.Str
+	case IntField:
+		return strconv.FormatInt(f.Int, 10)
+	case FloatField:
+		return strconv.FormatFloat(f.Float, 'g', -1, 64)
+	case BoolField:
+		return strconv.FormatBool(f.Int == 1)
+	case DurationField:
+		return time.Duration(f.Int).String()
+	case TimeField:
+		return f.Time.Format(time.RFC3339Nano)
+	case ErrorField:
+		if err, ok := f.Any.(error); ok && err != nil {
+			return err.Error()
+		}
+		return "<nil>"
+	default:
+		return fmt.Sprintf("%+v", f.Any)
+	}
+}
+
+func (e event) addField(f Field) Event {
+	fields := make([]Field, len(e.fields), len(e.fields)+1)
+	copy(fields, e.fields)
+	e.fields = append(fields, f)
+	return e
+}
+
+func (e event) Str(key, value string) Event {
+	return e.addField(Field{Key: key, Kind: StringField, Str: value})
+}
+
+func (e event) Int(key string, value int) Event {
+	return e.addField(Field{Key: key, Kind: IntField, Int: int64(value)})
+}
+
+func (e event) Int64(key string, value int64) Event {
+	return e.addField(Field{Key: key, Kind: IntField, Int: value})
+}
+
+func (e event) Float(key string, value float64) Event {
+	return e.addField(Field{Key: key, Kind: FloatField, Float: value})
+}
+
+func (e event) Bool(key string, value bool) Event {
+	var i int64
+	if value {
+		i = 1
+	}
+	return e.addField(Field{Key: key, Kind: BoolField, Int: i})
+}
+
+func (e event) Dur(key string, value time.Duration) Event {
+	return e.addField(Field{Key: key, Kind: DurationField, Int: int64(value)})
+}
+
+func (e event) Time(key string, value time.Time) Event {
+	return e.addField(Field{Key: key, Kind: TimeField, Time: value})
+}
+
+func (e event) Any(key string, value interface{}) Event {
+	return e.addField(Field{Key: key, Kind: AnyField, Any: value})
+}
+
+func (e event) Err(key string, err error) Event {
+	return e.addField(Field{Key: key, Kind: ErrorField, Any: err})
+}
@@ -1,11 +1,14 @@
 package log
 
+// Filter decides if a log entry should be written.
 type Filter interface {
-	CanWrite(msg string) bool
+	CanWrite(Entry) bool
 }
 
+// NoFilter lets every log entry pass.
 type NoFilter struct{}
 
-func (f *NoFilter) CanWrite(_ string) bool {
+// CanWrite always returns true.
+func (f *NoFilter) CanWrite(_ Entry) bool {
 	return true
 }
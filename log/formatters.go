@@ -3,16 +3,19 @@ package log
 import (
 	"encoding/json"
 	"fmt"
-	"runtime/debug"
+	"math"
+	"os"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
-// Formatter can format a log event into a string.
+// Formatter can format a log entry into a string.
 type Formatter interface {
-	Format(event) string
+	Format(Entry) string
 }
 
 // --------------------------------
@@ -50,58 +53,285 @@ func logFmt(fontWeight, colorCode int) string {
 	return fmt.Sprintf("\033[%s;%sm", strconv.Itoa(fontWeight), strconv.Itoa(colorCode))
 }
 
-func logColor(lvl Level) (string, string) {
-	switch lvl {
-	case Debug:
-		return logFmt(normal, darkGray), logFmt(normal, darkGray)
-	case Info:
-		return logFmt(normal, lightGray), reset
-	case Notice:
-		return logFmt(normal, lightGreen), reset
-	case Warning:
-		return logFmt(normal, lightYellow), reset
-	case Error:
-		return logFmt(normal, lightRed), reset
-	case Alert:
-		return logFmt(normal, red), reset
-	case Critical:
-		return logFmt(normal, lightRed), logFmt(normal, lightRed)
-	case Emergency:
-		return logFmt(normal, red), logFmt(normal, red)
-	case Default:
-		return logFmt(normal, white), reset
-	default:
-		return logFmt(normal, white), reset
+// LevelColors are the ANSI escape sequences which colour a log line of a certain level.
+type LevelColors struct {
+	// Severity colours the short name of the level, e.g. "\033[0;91m".
+	Severity string
+	// Text colours the rest of the log line. Use "\033[0m" to reset it to the terminal's default.
+	Text string
+}
+
+// Palette maps log levels to their colours.
+type Palette map[Level]LevelColors
+
+// DefaultPalette returns the default colours of the Console formatter.
+func DefaultPalette() Palette {
+	return Palette{
+		Default:   {Severity: logFmt(normal, white), Text: reset},
+		Debug:     {Severity: logFmt(normal, darkGray), Text: logFmt(normal, darkGray)},
+		Info:      {Severity: logFmt(normal, lightGray), Text: reset},
+		Notice:    {Severity: logFmt(normal, lightGreen), Text: reset},
+		Warning:   {Severity: logFmt(normal, lightYellow), Text: reset},
+		Error:     {Severity: logFmt(normal, lightRed), Text: reset},
+		Critical:  {Severity: logFmt(normal, lightRed), Text: logFmt(normal, lightRed)},
+		Alert:     {Severity: logFmt(normal, red), Text: reset},
+		Emergency: {Severity: logFmt(normal, red), Text: logFmt(normal, red)},
 	}
 }
 
-func logLevel(lvl Level) string {
-	severityColor, textColor := logColor(lvl)
-	return fmt.Sprintf("%s[%s]%s", severityColor, lvl.Short(), textColor)
+var defaultPalette = DefaultPalette()
+
+// ColorMode decides if the Console formatter writes ANSI colour escape sequences.
+type ColorMode int
+
+const (
+	// ColorAuto writes colours if the output is a terminal. The NO_COLOR environment variable
+	// disables and the FORCE_COLOR environment variable enables colours regardless of the output.
+	ColorAuto ColorMode = iota
+	// ColorAlways always writes colours.
+	ColorAlways
+	// ColorNever never writes colours.
+	ColorNever
+)
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
 
 // Console formats an event into a colour formatted human readable text.
 type Console struct {
+	// TimeFormat is the layout of the timestamp (default: 2006-01-02 15:04:05.000).
+	TimeFormat string
+	// Location is the time zone of the timestamp (default: UTC).
+	Location *time.Location
+
+	// ColorMode decides if colours get written (default: ColorAuto).
+	ColorMode ColorMode
+	// Output is the file which the exporter writes to. ColorAuto checks
+	// if it is a terminal (default: os.Stdout).
+	Output *os.File
+	// Palette overrides the colours of the log levels (default: DefaultPalette).
+	// Levels which are missing from the palette are not coloured.
+	Palette Palette
+
+	// HideServiceContext hides the service name and version.
+	HideServiceContext bool
+	// HideLabels hides the labels.
+	HideLabels bool
+	// HideHTTPRequest hides the summary of the HTTP request.
+	HideHTTPRequest bool
+	// HideData hides the data.
+	HideData bool
+
+	// autoColorOnce determines the colour support of ColorAuto once,
+	// because it checks the environment and the output file.
+	autoColorOnce sync.Once
+	autoColor     bool
 }
 
-// Format formats a log event into the Stackdriver specific JSON schema.
-func (f *Console) Format(e event) string {
+// Format formats a log entry into a colour formatted human readable text.
+func (f *Console) Format(e Entry) string {
 	errMsg := ""
-	if e.err != nil {
-		errMsg = fmt.Sprintf("\n\n%s\n\n%s", e.err.Error(), debug.Stack())
+	if e.Error != nil {
+		errMsg = fmt.Sprintf("\n\n%s%s\n\n%s", e.Error.Error(), consoleCauses(e.ErrorChain()), e.StackTrace())
+	}
+
+	colors := LevelColors{}
+	timestampColor, traceColor, resetColor := "", "", ""
+	if f.colored() {
+		palette := f.Palette
+		if palette == nil {
+			palette = defaultPalette
+		}
+		colors = palette[e.Level]
+		timestampColor, traceColor, resetColor = logFmt(normal, blue), logFmt(normal, lightGray), reset
 	}
 
 	return fmt.Sprintf(
-		"%s[%s]%s %s[%s] %s %s%s%s",
-		logFmt(normal, blue),
-		time.Now().UTC().Format(timeFormat),
-		reset,
-		logFmt(normal, lightGray),
-		e.traceID.String(),
-		logLevel(e.level),
-		e.message,
+		"%s[%s]%s %s[%s] %s[%s]%s %s%s%s%s%s%s%s",
+		timestampColor,
+		f.timestamp(e.Timestamp),
+		resetColor,
+		traceColor,
+		e.TraceID.String(),
+		colors.Severity,
+		e.Level.Short(),
+		colors.Text,
+		f.serviceContext(e),
+		consoleSourceLocation(e.SourceLocation),
+		e.Message,
+		consoleFields(e.Fields),
+		f.details(e),
 		errMsg,
-		reset)
+		resetColor)
+}
+
+// colored decides if the log line gets coloured. The ColorAuto decision is only made once
+// per formatter, so a Console must not be copied after its first use.
+func (f *Console) colored() bool {
+	switch f.ColorMode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+
+	f.autoColorOnce.Do(func() {
+		f.autoColor = f.detectColor()
+	})
+	return f.autoColor
+}
+
+// detectColor decides if ColorAuto writes colours based on the environment and the output file.
+func (f *Console) detectColor() bool {
+	if len(os.Getenv("NO_COLOR")) > 0 {
+		return false
+	}
+	if force := os.Getenv("FORCE_COLOR"); len(force) > 0 && force != "0" && force != "false" {
+		return true
+	}
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	output := f.Output
+	if output == nil {
+		output = os.Stdout
+	}
+	return isTerminal(output)
+}
+
+func (f *Console) serviceContext(e Entry) string {
+	if f.HideServiceContext || (len(e.ServiceName) == 0 && len(e.ServiceVersion) == 0) {
+		return ""
+	}
+	if len(e.ServiceVersion) == 0 {
+		return "[" + e.ServiceName + "] "
+	}
+	return "[" + e.ServiceName + "@" + e.ServiceVersion + "] "
+}
+
+// details returns the labels, HTTP request and data of an entry, each on its own indented line.
+func (f *Console) details(e Entry) string {
+	var str strings.Builder
+
+	if !f.HideLabels && len(e.Labels) > 0 {
+		keys := make([]string, 0, len(e.Labels))
+		for key := range e.Labels {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		str.WriteString("\n  labels:")
+		for _, key := range keys {
+			str.WriteString(" ")
+			str.WriteString(key)
+			str.WriteString("=")
+			str.WriteString(consoleValue(e.Labels[key]))
+		}
+	}
+
+	if !f.HideHTTPRequest && e.HasHTTPRequest() {
+		req := e.HTTPRequest
+		str.WriteString("\n  request: ")
+		str.WriteString(req.RequestMethod)
+		str.WriteString(" ")
+		str.WriteString(req.RequestURL)
+		if req.Status > 0 {
+			str.WriteString(" ")
+			str.WriteString(strconv.Itoa(req.Status))
+		}
+		if len(req.Latency) > 0 {
+			str.WriteString(" ")
+			str.WriteString(req.Latency)
+		}
+		if len(req.ResponseSize) > 0 {
+			str.WriteString(" ")
+			str.WriteString(req.ResponseSize)
+			str.WriteString("B")
+		}
+		if len(req.RemoteIP) > 0 {
+			str.WriteString(" from ")
+			str.WriteString(req.RemoteIP)
+		}
+	}
+
+	if !f.HideData && e.Data != nil {
+		str.WriteString("\n  data: ")
+		data, err := json.MarshalIndent(e.Data, "  ", "  ")
+		if err != nil {
+			str.WriteString(fmt.Sprintf("%+v", e.Data))
+		} else {
+			str.Write(data)
+		}
+	}
+
+	return str.String()
+}
+
+func (f *Console) timestamp(t time.Time) string {
+	layout := f.TimeFormat
+	if len(layout) == 0 {
+		layout = timeFormat
+	}
+	if f.Location != nil {
+		t = t.In(f.Location)
+	}
+	return t.Format(layout)
+}
+
+func consoleSourceLocation(l *SourceLocation) string {
+	if l == nil {
+		return ""
+	}
+	return filepath.Base(l.File) + ":" + strconv.Itoa(l.Line) + " "
+}
+
+func consoleValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " =\"\t\r\n") {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+func consoleCauses(chain []Cause) string {
+	if len(chain) < 2 {
+		return ""
+	}
+
+	var str strings.Builder
+	for _, cause := range chain[1:] {
+		str.WriteString("\n  caused by ")
+		str.WriteString(cause.Type)
+		str.WriteString(": ")
+		str.WriteString(cause.Message)
+		keys := make([]string, 0, len(cause.Fields))
+		for key := range cause.Fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			str.WriteString(" ")
+			str.WriteString(key)
+			str.WriteString("=")
+			str.WriteString(consoleValue(fmt.Sprintf("%v", cause.Fields[key])))
+		}
+	}
+	return str.String()
+}
+
+func consoleFields(fields []Field) string {
+	if len(fields) == 0 {
+		return ""
+	}
+
+	var str strings.Builder
+	for _, f := range fields {
+		str.WriteString(" ")
+		str.WriteString(f.Key)
+		str.WriteString("=")
+		str.WriteString(consoleValue(f.String()))
+	}
+	return str.String()
 }
 
 // --------------------------------
@@ -110,97 +340,283 @@ func (f *Console) Format(e event) string {
 
 // Stackdriver formats an event into the Stackdriver specific JSON format.
 type Stackdriver struct {
+	// ProjectID is the Google Cloud project which owns the traces. If it is set then
+	// trace IDs are written as projects/PROJECT_ID/traces/TRACE_ID, which links a log
+	// entry to its trace. Otherwise the project ID which has been set with SetEnvironment is used.
+	ProjectID string
 }
 
-func sortKeys(m map[string]string) []string {
-	keys := make([]string, 0, len(m))
-	for k := range m {
-		keys = append(keys, k)
+func appendHTTPRequest(dst []byte, req *HTTPRequest) []byte {
+	dst = append(dst, `{"requestMethod":`...)
+	dst = appendJSONString(dst, req.RequestMethod)
+	dst = append(dst, `,"requestUrl":`...)
+	dst = appendJSONString(dst, req.RequestURL)
+	dst = append(dst, `,"requestSize":`...)
+	dst = appendJSONString(dst, req.RequestSize)
+	if req.Status != 0 {
+		dst = append(dst, `,"status":`...)
+		dst = strconv.AppendInt(dst, int64(req.Status), 10)
 	}
-	sort.Strings(keys)
-
-	return keys
+	if len(req.ResponseSize) > 0 {
+		dst = append(dst, `,"responseSize":`...)
+		dst = appendJSONString(dst, req.ResponseSize)
+	}
+	dst = append(dst, `,"userAgent":`...)
+	dst = appendJSONString(dst, req.UserAgent)
+	dst = append(dst, `,"remoteIp":`...)
+	dst = appendJSONString(dst, req.RemoteIP)
+	dst = append(dst, `,"serverIp":`...)
+	dst = appendJSONString(dst, req.ServerIP)
+	dst = append(dst, `,"referer":`...)
+	dst = appendJSONString(dst, req.Referer)
+	if len(req.Latency) > 0 {
+		dst = append(dst, `,"latency":`...)
+		dst = appendJSONString(dst, req.Latency)
+	}
+	if req.CacheLookup {
+		dst = append(dst, `,"cacheLookup":true`...)
+	}
+	if req.CacheHit {
+		dst = append(dst, `,"cacheHit":true`...)
+	}
+	if req.CacheValidatedWithOriginServer {
+		dst = append(dst, `,"cacheValidatedWithOriginServer":true`...)
+	}
+	if len(req.CacheFillBytes) > 0 {
+		dst = append(dst, `,"cacheFillBytes":`...)
+		dst = appendJSONString(dst, req.CacheFillBytes)
+	}
+	dst = append(dst, `,"protocol":`...)
+	dst = appendJSONString(dst, req.Protocol)
+	return append(dst, '}')
 }
 
-func escapeJSON(str string) string {
-	b, err := json.Marshal(str)
+func appendJSONValue(dst []byte, value interface{}) []byte {
+	buffer, err := json.Marshal(value)
 	if err != nil {
-		panicOnErr(err, "Failed to escape JSON string.")
+		return appendJSONString(dst, fmt.Sprintf("Could not successfully serialize data object into JSON when writing this message.\n\nError: %+v", err))
 	}
-	s := string(b)
-	return s[1 : len(s)-1]
+	return append(dst, buffer...)
 }
 
-// Format formats a log event into the Stackdriver specific JSON schema.
-func (f *Stackdriver) Format(e event) string {
+func appendField(dst []byte, f Field) []byte {
+	switch f.Kind {
+	case StringField:
+		return appendJSONString(dst, f.Str)
+	case IntField:
+		return strconv.AppendInt(dst, f.Int, 10)
+	case BoolField:
+		return strconv.AppendBool(dst, f.Int == 1)
+	case FloatField:
+		if math.IsNaN(f.Float) || math.IsInf(f.Float, 0) {
+			return appendJSONString(dst, f.String())
+		}
+		return strconv.AppendFloat(dst, f.Float, 'g', -1, 64)
+	case TimeField:
+		dst = append(dst, '"')
+		dst = f.Time.AppendFormat(dst, time.RFC3339Nano)
+		return append(dst, '"')
+	case AnyField:
+		return appendJSONValue(dst, f.Any)
+	default:
+		return appendJSONString(dst, f.String())
+	}
+}
 
-	var str strings.Builder
-	str.WriteString("{")
-	str.WriteString(fmt.Sprintf("\"severity\":\"%s\"", e.level.String()))
+// appendMessage appends the message of an entry without a leading comma.
+// Entries with an error are marked as a reported error event for Google Cloud Error Reporting.
+func appendMessage(dst []byte, e Entry) []byte {
+	if e.Error == nil {
+		dst = append(dst, `"message":`...)
+		return appendJSONString(dst, e.Message)
+	}
 
-	if e.err == nil {
-		str.WriteString(fmt.Sprintf(",\"message\":\"%s\"", escapeJSON(e.message)))
-	} else {
-		str.WriteString(",\"@type\":\"type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent\"")
-		errMsg := fmt.Sprintf("%+v\n\n%s", e.err.Error(), debug.Stack())
-		if len(e.message) > 0 {
-			errMsg = e.message + "\n\nError:\n\n" + errMsg
-		}
-		str.WriteString(fmt.Sprintf(",\"message\":\"%s\"", escapeJSON(errMsg)))
+	dst = append(dst, `"@type":"type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent"`...)
+	errMsg := e.Error.Error()
+	if stack := e.StackTrace(); len(stack) > 0 {
+		errMsg += "\n\n" + stack
+	}
+	if len(e.Message) > 0 {
+		errMsg = e.Message + "\n\nError:\n\n" + errMsg
 	}
+	dst = append(dst, `,"message":`...)
+	return appendJSONString(dst, errMsg)
+}
 
-	if e.traceID.IsValid() {
-		str.WriteString(fmt.Sprintf(",\"logging.googleapis.com/trace_sampled\":\"true\",\"logging.googleapis.com/trace\":\"%s\"", e.traceID.String()))
+// appendSourceLocation appends the file, line and function of an entry's source location as a JSON object.
+func appendSourceLocation(dst []byte, l *SourceLocation) []byte {
+	dst = append(dst, `{"file":`...)
+	dst = appendJSONString(dst, l.File)
+	dst = append(dst, `,"line":"`...)
+	dst = strconv.AppendInt(dst, int64(l.Line), 10)
+	dst = append(dst, `","function":`...)
+	dst = appendJSONString(dst, l.Function)
+	return append(dst, '}')
+}
 
-		if e.spanID.IsValid() {
-			str.WriteString(fmt.Sprintf(",\"logging.googleapis.com/spanId\":\"%d\"", e.spanID.Decimal()))
+// appendErrorChain appends the message, type and fields of every error in the chain of an entry's error.
+func appendErrorChain(dst []byte, e Entry) []byte {
+	if e.Error == nil {
+		return dst
+	}
+	dst = append(dst, `,"errorChain":[`...)
+	for i, cause := range e.ErrorChain() {
+		if i > 0 {
+			dst = append(dst, ',')
 		}
+		dst = append(dst, `{"message":`...)
+		dst = appendJSONString(dst, cause.Message)
+		dst = append(dst, `,"type":`...)
+		dst = appendJSONString(dst, cause.Type)
+		if len(cause.Fields) > 0 {
+			dst = append(dst, `,"fields":`...)
+			dst = appendJSONValue(dst, cause.Fields)
+		}
+		dst = append(dst, '}')
 	}
+	return append(dst, ']')
+}
 
-	if len(e.serviceName) > 0 {
-		str.WriteString(fmt.Sprintf(",\"serviceContext.service\":\"%s\"", e.serviceName))
+// appendServiceContext appends the serviceContext object which Google Cloud Error Reporting
+// uses to group errors by service and version.
+func appendServiceContext(dst []byte, e Entry) []byte {
+	if len(e.ServiceName) == 0 && len(e.ServiceVersion) == 0 {
+		return dst
 	}
+	dst = append(dst, `,"serviceContext":{"service":`...)
+	dst = appendJSONString(dst, e.ServiceName)
+	if len(e.ServiceVersion) > 0 {
+		dst = append(dst, `,"version":`...)
+		dst = appendJSONString(dst, e.ServiceVersion)
+	}
+	return append(dst, '}')
+}
 
-	if len(e.serviceVersion) > 0 {
-		str.WriteString(fmt.Sprintf(",\"serviceContext.version\":\"%s\"", e.serviceVersion))
+// appendReportLocation appends the context object with the location where an error has been logged.
+func appendReportLocation(dst []byte, e Entry) []byte {
+	if e.ReportLocation == nil {
+		return dst
 	}
+	dst = append(dst, `,"context":{"reportLocation":{"filePath":`...)
+	dst = appendJSONString(dst, e.ReportLocation.FilePath)
+	dst = append(dst, `,"lineNumber":`...)
+	dst = strconv.AppendInt(dst, int64(e.ReportLocation.LineNumber), 10)
+	dst = append(dst, `,"functionName":`...)
+	dst = appendJSONString(dst, e.ReportLocation.FunctionName)
+	return append(dst, "}}"...)
+}
 
-	if e.labels != nil && len(e.labels) > 0 {
-		str.WriteString(",\"logging.googleapis.com/labels\":{")
+// reservedFieldPrefix is put in front of field keys which clash with a property of the formatter,
+// so that a field cannot override e.g. the severity or message of an entry.
+const reservedFieldPrefix = "fields."
 
-		isFirst := true
-		sortedKeys := sortKeys(e.labels)
+// isStackdriverKey checks if a key is a property of the Stackdriver format.
+func isStackdriverKey(key string) bool {
+	switch key {
+	case "severity", "message", "@type", "timestamp", "serviceContext", "context", "httpRequest", "errorChain", "data":
+		return true
+	}
+	return strings.HasPrefix(key, "logging.googleapis.com/")
+}
 
-		for _, key := range sortedKeys {
-			value := e.labels[key]
-			if !isFirst {
-				str.WriteString(",")
-			}
-			str.WriteString(fmt.Sprintf("\"%s\":\"%s\"", key, value))
-			isFirst = false
-		}
+// fieldKey returns the key of a field, prefixed with reservedFieldPrefix if it is reserved.
+func fieldKey(key string, reserved func(string) bool) string {
+	if reserved(key) {
+		return reservedFieldPrefix + key
+	}
+	return key
+}
 
-		str.WriteString("}")
+// appendFieldsAndData appends the fields and data of an entry as comma separated JSON properties.
+// Fields with a reserved key are written with the reservedFieldPrefix.
+func appendFieldsAndData(dst []byte, e Entry, reserved func(string) bool) []byte {
+	for _, field := range e.Fields {
+		dst = appendJSONKey(dst, fieldKey(field.Key, reserved))
+		dst = appendField(dst, field)
 	}
 
-	if e.hasHTTPRequest {
-		buffer, err := json.Marshal(e.httpRequest)
-		if err == nil {
-			req := string(buffer)
-			str.WriteString(fmt.Sprintf(",\"httpRequest\":%s", req))
+	if e.Data != nil {
+		dst = append(dst, `,"data":`...)
+		dst = appendJSONValue(dst, e.Data)
+	}
+	return dst
+}
+
+// Format formats a log entry into the Stackdriver specific JSON schema.
+// Fields are written as top level JSON properties. Fields which clash with a property
+// of the schema are prefixed with "fields.", e.g. "fields.severity".
+func (f *Stackdriver) Format(e Entry) string {
+	buf := getBuffer()
+	b := buf.bytes
+
+	b = append(b, `{"severity":"`...)
+	b = append(b, e.Level.String()...)
+	b = append(b, '"')
+
+	if !e.Timestamp.IsZero() {
+		b = append(b, `,"timestamp":"`...)
+		b = e.Timestamp.UTC().AppendFormat(b, time.RFC3339Nano)
+		b = append(b, '"')
+	}
+
+	b = append(b, ',')
+	b = appendMessage(b, e)
+
+	if e.TraceID.IsValid() {
+		b = append(b, `,"logging.googleapis.com/trace_sampled":`...)
+		b = strconv.AppendBool(b, e.TraceSampled)
+		b = append(b, `,"logging.googleapis.com/trace":`...)
+		projectID := f.ProjectID
+		if len(projectID) == 0 {
+			projectID = e.ProjectID
+		}
+		if len(projectID) > 0 {
+			b = appendJSONString(b, "projects/"+projectID+"/traces/"+e.TraceID.String())
+		} else {
+			b = append(b, '"')
+			b = appendHex(b, e.TraceID[:])
+			b = append(b, '"')
+		}
+
+		if e.SpanID.IsValid() {
+			b = append(b, `,"logging.googleapis.com/spanId":"`...)
+			b = appendHex(b, e.SpanID[:])
+			b = append(b, '"')
 		}
 	}
 
-	if e.data != nil {
-		var dataStr string
-		buffer, err := json.Marshal(e.data)
-		if err != nil {
-			dataStr = fmt.Sprintf("\"Could not successfully serialize data object into JSON when writing this message.\n\nError: %+v\"", err)
+	if e.SourceLocation != nil {
+		b = append(b, `,"logging.googleapis.com/sourceLocation":`...)
+		b = appendSourceLocation(b, e.SourceLocation)
+	}
+
+	b = appendServiceContext(b, e)
+	b = appendReportLocation(b, e)
+	b = appendErrorChain(b, e)
+
+	if len(e.Labels) > 0 {
+		b = append(b, `,"logging.googleapis.com/labels":{`...)
+		for i, key := range buf.sortedKeys(e.Labels) {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = appendJSONString(b, key)
+			b = append(b, ':')
+			b = appendJSONString(b, e.Labels[key])
 		}
-		dataStr = string(buffer)
-		str.WriteString(fmt.Sprintf(",\"data\":%s", dataStr))
+		b = append(b, '}')
 	}
 
-	str.WriteString("}")
-	return str.String()
+	if e.HasHTTPRequest() {
+		b = append(b, `,"httpRequest":`...)
+		b = appendHTTPRequest(b, e.HTTPRequest)
+	}
+
+	b = appendFieldsAndData(b, e, isStackdriverKey)
+	b = append(b, '}')
+	output := string(b)
+
+	buf.bytes = b
+	putBuffer(buf)
+	return output
 }
@@ -1,7 +1,16 @@
 package log
 
 import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/dusted-go/diagnostic/gcp"
+	"github.com/dusted-go/diagnostic/trace"
 )
 
 type testCase struct {
@@ -46,11 +55,11 @@ func Test_Stackdriver_WithoutAnyExtraSettings_FormatsCorrectly(t *testing.T) {
 
 	for _, testCase := range testCases {
 		if actual := stackdriver.Format(
-			event{
-				level:   testCase.Level,
-				message: testCase.Msg,
-				err:     testCase.Err,
-				data:    testCase.Data,
+			Entry{
+				Level:   testCase.Level,
+				Message: testCase.Msg,
+				Error:   testCase.Err,
+				Data:    testCase.Data,
 			}); actual != testCase.Expected {
 			t.Errorf("\nExpected:\n%s,\nActual:\n%s", testCase.Expected, actual)
 		}
@@ -69,15 +78,15 @@ func Test_Stackdriver_WithServiceContext_FormatsCorrectly(t *testing.T) {
 			Street:      "x",
 			Postcode:    "Y"}}
 
-	expected := "{\"severity\":\"INFO\",\"message\":\"this is a stupid message\",\"serviceContext.service\":\"foo-bar\",\"serviceContext.version\":\"v1.0.0\",\"data\":{\"FirstName\":\"Sue\",\"LastName\":\"Doe\",\"Pets\":null,\"Age\":45,\"Address\":{\"HouseNumber\":3,\"Street\":\"x\",\"Postcode\":\"Y\"}}}"
+	expected := "{\"severity\":\"INFO\",\"message\":\"this is a stupid message\",\"serviceContext\":{\"service\":\"foo-bar\",\"version\":\"v1.0.0\"},\"data\":{\"FirstName\":\"Sue\",\"LastName\":\"Doe\",\"Pets\":null,\"Age\":45,\"Address\":{\"HouseNumber\":3,\"Street\":\"x\",\"Postcode\":\"Y\"}}}"
 
-	if actual := stackdriver.Format(event{
-		serviceName:    "foo-bar",
-		serviceVersion: "v1.0.0",
-		level:          Info,
-		message:        "this is a stupid message",
-		err:            nil,
-		data:           data,
+	if actual := stackdriver.Format(Entry{
+		ServiceName:    "foo-bar",
+		ServiceVersion: "v1.0.0",
+		Level:          Info,
+		Message:        "this is a stupid message",
+		Error:          nil,
+		Data:           data,
 	}); actual != expected {
 		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
 	}
@@ -97,12 +106,12 @@ func Test_Stackdriver_WithLabels_FormatsCorrectly(t *testing.T) {
 
 	expected := "{\"severity\":\"INFO\",\"message\":\"this is a stupid message\",\"logging.googleapis.com/labels\":{\"B\":\"b\",\"a\":\"A\"},\"data\":{\"FirstName\":\"Sue\",\"LastName\":\"Doe\",\"Pets\":null,\"Age\":45,\"Address\":{\"HouseNumber\":3,\"Street\":\"x\",\"Postcode\":\"Y\"}}}"
 
-	if actual := stackdriver.Format(event{
-		level:   Info,
-		message: "this is a stupid message",
-		err:     nil,
-		data:    data,
-		labels: map[string]string{
+	if actual := stackdriver.Format(Entry{
+		Level:   Info,
+		Message: "this is a stupid message",
+		Error:   nil,
+		Data:    data,
+		Labels: map[string]string{
 			"a": "A",
 			"B": "b",
 		},
@@ -123,16 +132,16 @@ func Test_Stackdriver_WithLabelsAndServiceContext_FormatsCorrectly(t *testing.T)
 			Street:      "x",
 			Postcode:    "Y"}}
 
-	expected := "{\"severity\":\"INFO\",\"message\":\"this is a stupid message\",\"serviceContext.service\":\"foo-bar\",\"serviceContext.version\":\"v1.0.0\",\"logging.googleapis.com/labels\":{\"B\":\"b\",\"a\":\"A\"},\"data\":{\"FirstName\":\"Sue\",\"LastName\":\"Doe\",\"Pets\":null,\"Age\":45,\"Address\":{\"HouseNumber\":3,\"Street\":\"x\",\"Postcode\":\"Y\"}}}"
+	expected := "{\"severity\":\"INFO\",\"message\":\"this is a stupid message\",\"serviceContext\":{\"service\":\"foo-bar\",\"version\":\"v1.0.0\"},\"logging.googleapis.com/labels\":{\"B\":\"b\",\"a\":\"A\"},\"data\":{\"FirstName\":\"Sue\",\"LastName\":\"Doe\",\"Pets\":null,\"Age\":45,\"Address\":{\"HouseNumber\":3,\"Street\":\"x\",\"Postcode\":\"Y\"}}}"
 
-	if actual := stackdriver.Format(event{
-		serviceName:    "foo-bar",
-		serviceVersion: "v1.0.0",
-		level:          Info,
-		message:        "this is a stupid message",
-		err:            nil,
-		data:           data,
-		labels: map[string]string{
+	if actual := stackdriver.Format(Entry{
+		ServiceName:    "foo-bar",
+		ServiceVersion: "v1.0.0",
+		Level:          Info,
+		Message:        "this is a stupid message",
+		Error:          nil,
+		Data:           data,
+		Labels: map[string]string{
 			"a": "A",
 			"B": "b",
 		},
@@ -153,13 +162,12 @@ func Test_Stackdriver_WithLabelsAndServiceContextAndHttpRequest_FormatsCorrectly
 			Street:      "x",
 			Postcode:    "Y"}}
 
-	expected := "{\"severity\":\"INFO\",\"message\":\"this is a stupid message\",\"serviceContext.service\":\"foo-bar\",\"serviceContext.version\":\"v1.0.0\",\"logging.googleapis.com/labels\":{\"B\":\"b\",\"a\":\"A\"},\"httpRequest\":{\"requestMethod\":\"GET\",\"requestUrl\":\"http://example.org/\",\"requestSize\":\"132\",\"userAgent\":\"abc\",\"remoteIp\":\"127.0.0.1\",\"serverIp\":\"\",\"referer\":\"google.com\",\"protocol\":\"HTTP/1.1\"},\"data\":{\"FirstName\":\"Sue\",\"LastName\":\"Doe\",\"Pets\":null,\"Age\":45,\"Address\":{\"HouseNumber\":3,\"Street\":\"x\",\"Postcode\":\"Y\"}}}"
+	expected := "{\"severity\":\"INFO\",\"message\":\"this is a stupid message\",\"serviceContext\":{\"service\":\"foo-bar\",\"version\":\"v1.0.0\"},\"logging.googleapis.com/labels\":{\"B\":\"b\",\"a\":\"A\"},\"httpRequest\":{\"requestMethod\":\"GET\",\"requestUrl\":\"http://example.org/\",\"requestSize\":\"132\",\"userAgent\":\"abc\",\"remoteIp\":\"127.0.0.1\",\"serverIp\":\"\",\"referer\":\"google.com\",\"protocol\":\"HTTP/1.1\"},\"data\":{\"FirstName\":\"Sue\",\"LastName\":\"Doe\",\"Pets\":null,\"Age\":45,\"Address\":{\"HouseNumber\":3,\"Street\":\"x\",\"Postcode\":\"Y\"}}}"
 
-	if actual := stackdriver.Format(event{
-		serviceName:    "foo-bar",
-		serviceVersion: "v1.0.0",
-		hasHTTPRequest: true,
-		httpRequest: httpRequest{
+	if actual := stackdriver.Format(Entry{
+		ServiceName:    "foo-bar",
+		ServiceVersion: "v1.0.0",
+		HTTPRequest: &HTTPRequest{
 			RequestMethod: "GET",
 			RequestURL:    "http://example.org/",
 			RequestSize:   "132",
@@ -169,11 +177,11 @@ func Test_Stackdriver_WithLabelsAndServiceContextAndHttpRequest_FormatsCorrectly
 			Referer:       "google.com",
 			Protocol:      "HTTP/1.1",
 		},
-		level:   Info,
-		message: "this is a stupid message",
-		err:     nil,
-		data:    data,
-		labels: map[string]string{
+		Level:   Info,
+		Message: "this is a stupid message",
+		Error:   nil,
+		Data:    data,
+		Labels: map[string]string{
 			"a": "A",
 			"B": "b",
 		},
@@ -181,3 +189,310 @@ func Test_Stackdriver_WithLabelsAndServiceContextAndHttpRequest_FormatsCorrectly
 		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
 	}
 }
+
+func Test_Stackdriver_WithFields_FormatsCorrectly(t *testing.T) {
+	stackdriver := Stackdriver{}
+
+	e := event{level: Info, message: "fields"}.
+		Str("method", "GET").
+		Int("status", 200).
+		Int64("bytes", 1024).
+		Float("ratio", 0.5).
+		Bool("cached", true).
+		Dur("latency", 1500*time.Millisecond).
+		Time("at", time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)).
+		Any("address", Address{HouseNumber: 1, Street: "Foo", Postcode: "Bar"}).
+		Err("cause", errors.New("oops \"quoted\"")).(event)
+
+	expected := "{\"severity\":\"INFO\",\"message\":\"fields\",\"method\":\"GET\",\"status\":200,\"bytes\":1024,\"ratio\":0.5,\"cached\":true,\"latency\":\"1.5s\",\"at\":\"2021-03-04T05:06:07Z\",\"address\":{\"HouseNumber\":1,\"Street\":\"Foo\",\"Postcode\":\"Bar\"},\"cause\":\"oops \\\"quoted\\\"\"}"
+
+	if actual := stackdriver.Format(e.entry(time.Time{})); actual != expected {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
+	}
+}
+
+func Test_Console_Fields_FormatsKeyValuePairs(t *testing.T) {
+	fields := event{}.
+		Str("method", "GET").
+		Str("path", "/foo bar").
+		Int("status", 200).
+		Bool("cached", false).
+		Dur("latency", 250*time.Millisecond).(event).fields
+
+	expected := " method=GET path=\"/foo bar\" status=200 cached=false latency=250ms"
+
+	if actual := consoleFields(fields); actual != expected {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
+	}
+}
+
+func Benchmark_Stackdriver_Format_Message(b *testing.B) {
+	stackdriver := Stackdriver{}
+	entry := Entry{Level: Info, Message: "a simple log message"}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = stackdriver.Format(entry)
+	}
+}
+
+func Benchmark_Stackdriver_Format_FullEntry(b *testing.B) {
+	stackdriver := Stackdriver{}
+	entry := event{
+		level:          Info,
+		message:        "a \"full\" log message\nwith a new line",
+		serviceName:    "foo-bar",
+		serviceVersion: "v1.0.0",
+		traceID:        trace.ID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		spanID:         trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+		labels:         map[string]string{"a": "A", "B": "b", "env": "prod"},
+		hasHTTPRequest: true,
+		httpRequest: HTTPRequest{
+			RequestMethod: "GET",
+			RequestURL:    "http://example.org/",
+			RequestSize:   "132",
+			UserAgent:     "abc",
+			RemoteIP:      "127.0.0.1",
+			Referer:       "google.com",
+			Protocol:      "HTTP/1.1",
+		},
+	}.
+		Str("method", "GET").
+		Int("status", 200).
+		Float("ratio", 0.5).
+		Bool("cached", true).
+		Time("at", time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)).(event).
+		entry(time.Time{})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = stackdriver.Format(entry)
+	}
+}
+
+func Test_Stackdriver_WithTrace_FormatsSamplingDecision(t *testing.T) {
+	stackdriver := Stackdriver{}
+
+	sc, err := trace.ParseTraceparent("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := event{level: Info, message: "traced"}.SetSpanContext(sc).(event)
+
+	expected := "{\"severity\":\"INFO\",\"message\":\"traced\",\"logging.googleapis.com/trace_sampled\":false,\"logging.googleapis.com/trace\":\"0af7651916cd43dd8448eb211c80319c\",\"logging.googleapis.com/spanId\":\"b7ad6b7169203331\"}"
+	if actual := stackdriver.Format(e.entry(time.Time{})); actual != expected {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
+	}
+
+	sc.Flags = sc.Flags.WithSampled(true)
+	e = e.SetSpanContext(sc).(event)
+	expected = strings.Replace(expected, "trace_sampled\":false", "trace_sampled\":true", 1)
+	if actual := stackdriver.Format(e.entry(time.Time{})); actual != expected {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
+	}
+}
+
+func Test_Entry_WithoutSamplingDecision_IsSampled(t *testing.T) {
+	traceID, _ := trace.ParseID("0af7651916cd43dd8448eb211c80319c")
+	e := event{}.SetTraceID(traceID).(event)
+	if !e.entry(time.Time{}).TraceSampled {
+		t.Error("Expected a trace without a sampling decision to be sampled.")
+	}
+
+	e = e.SetTraceSampled(false).(event)
+	if e.entry(time.Time{}).TraceSampled {
+		t.Error("Expected the sampling decision to be kept.")
+	}
+}
+
+func Test_Stackdriver_WithProjectID_FormatsFullyQualifiedTrace(t *testing.T) {
+	stackdriver := Stackdriver{ProjectID: "my-project"}
+
+	sc, err := trace.ParseTraceparent("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := event{level: Info, message: "traced"}.SetSpanContext(sc).(event)
+
+	expected := "{\"severity\":\"INFO\",\"message\":\"traced\",\"logging.googleapis.com/trace_sampled\":true,\"logging.googleapis.com/trace\":\"projects/my-project/traces/0af7651916cd43dd8448eb211c80319c\",\"logging.googleapis.com/spanId\":\"b7ad6b7169203331\"}"
+	if actual := stackdriver.Format(e.entry(time.Time{})); actual != expected {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
+	}
+}
+
+func Test_Stackdriver_WithProjectID_EscapesProjectID(t *testing.T) {
+	stackdriver := Stackdriver{ProjectID: "my\"project"}
+
+	traceID, _ := trace.ParseID("0af7651916cd43dd8448eb211c80319c")
+	e := event{level: Info, message: "traced"}.SetTraceID(traceID).(event)
+
+	expected := "{\"severity\":\"INFO\",\"message\":\"traced\",\"logging.googleapis.com/trace_sampled\":true,\"logging.googleapis.com/trace\":\"projects/my\\\"project/traces/0af7651916cd43dd8448eb211c80319c\"}"
+	if actual := stackdriver.Format(e.entry(time.Time{})); actual != expected {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
+	}
+}
+
+func Test_Stackdriver_WithEnvironment_UsesProjectIDOfEvent(t *testing.T) {
+	stackdriver := Stackdriver{}
+
+	sc, err := trace.ParseTraceparent("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := event{level: Info, message: "traced"}.
+		SetEnvironment(gcp.Environment{ProjectID: "env-project", ServiceName: "api"}).
+		SetSpanContext(sc).(event)
+
+	expected := "{\"severity\":\"INFO\",\"message\":\"traced\",\"logging.googleapis.com/trace_sampled\":true,\"logging.googleapis.com/trace\":\"projects/env-project/traces/0af7651916cd43dd8448eb211c80319c\",\"logging.googleapis.com/spanId\":\"b7ad6b7169203331\",\"serviceContext\":{\"service\":\"api\"}}"
+	if actual := stackdriver.Format(e.entry(time.Time{})); actual != expected {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
+	}
+}
+
+func Test_Stackdriver_WithReportLocation_WritesErrorContext(t *testing.T) {
+	stackdriver := Stackdriver{}
+	e := event{level: Error}.
+		SetServiceName("svc \"quoted\"").(event).
+		entry(time.Time{})
+	e.ReportLocation = &ReportLocation{FilePath: "/src/main.go", LineNumber: 42, FunctionName: "main.run"}
+
+	expected := "{\"severity\":\"ERROR\",\"message\":\"\",\"serviceContext\":{\"service\":\"svc \\\"quoted\\\"\"},\"context\":{\"reportLocation\":{\"filePath\":\"/src/main.go\",\"lineNumber\":42,\"functionName\":\"main.run\"}}}"
+	if actual := stackdriver.Format(e); actual != expected {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
+	}
+}
+
+func Test_Stackdriver_WithSourceLocation_FormatsCorrectly(t *testing.T) {
+	stackdriver := Stackdriver{}
+	e := Entry{
+		Level:          Info,
+		Message:        "located",
+		SourceLocation: &SourceLocation{File: "/src/app/main.go", Line: 12, Function: "main.main"},
+	}
+
+	expected := "{\"severity\":\"INFO\",\"message\":\"located\",\"logging.googleapis.com/sourceLocation\":{\"file\":\"/src/app/main.go\",\"line\":\"12\",\"function\":\"main.main\"}}"
+	if actual := stackdriver.Format(e); actual != expected {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
+	}
+}
+
+func Test_Console_WithSourceLocation_WritesFileAndLine(t *testing.T) {
+	console := Console{}
+	e := Entry{
+		Level:          Info,
+		Message:        "located",
+		SourceLocation: &SourceLocation{File: "/src/app/main.go", Line: 12, Function: "main.main"},
+	}
+
+	if actual := console.Format(e); !strings.Contains(actual, " main.go:12 located") {
+		t.Errorf("Expected the source location before the message, but got:\n%s", actual)
+	}
+}
+
+func Test_Console_WithTimeFormatAndLocation_FormatsTimestamp(t *testing.T) {
+	timestamp := time.Date(2021, 6, 1, 10, 30, 0, 0, time.UTC)
+
+	if actual := (&Console{}).Format(Entry{Timestamp: timestamp}); !strings.Contains(actual, "[2021-06-01 10:30:00.000]") {
+		t.Errorf("Expected the default time format in UTC, but got:\n%s", actual)
+	}
+
+	console := Console{TimeFormat: time.Kitchen, Location: time.FixedZone("CEST", 2*60*60)}
+	if actual := console.Format(Entry{Timestamp: timestamp}); !strings.Contains(actual, "[12:30PM]") {
+		t.Errorf("Expected the custom time format and zone, but got:\n%s", actual)
+	}
+}
+
+func Test_Console_WithDetails_WritesLabelsRequestAndData(t *testing.T) {
+	e := event{level: Info, message: "handled"}.
+		SetServiceName("api").
+		SetServiceVersion("v1.2.0").
+		AddLabel("region", "eu").
+		AddLabel("env", "dev test").
+		SetHTTPRequest(&http.Request{Method: "GET", Host: "example.org", RequestURI: "/pets", RemoteAddr: "127.0.0.1:1234"}).
+		SetHTTPResponse(200, 512, 1500*time.Millisecond).
+		SetData(Address{HouseNumber: 3, Street: "x", Postcode: "Y"}).(event).
+		entry(time.Time{})
+
+	actual := (&Console{}).Format(e)
+	for _, expected := range []string{
+		" [api@v1.2.0] handled",
+		"\n  labels: env=\"dev test\" region=eu",
+		"\n  request: GET example.org/pets 200 1.5s 512B from 127.0.0.1",
+		"\n  data: {\n    \"HouseNumber\": 3,\n    \"Street\": \"x\",\n    \"Postcode\": \"Y\"\n  }",
+	} {
+		if !strings.Contains(actual, expected) {
+			t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
+		}
+	}
+
+	hidden := Console{HideServiceContext: true, HideLabels: true, HideHTTPRequest: true, HideData: true}
+	if actual := hidden.Format(e); strings.Contains(actual, "api@") || strings.Contains(actual, "\n") {
+		t.Errorf("Expected all details to be hidden, but got:\n%s", actual)
+	}
+}
+
+func Test_Console_ColorMode(t *testing.T) {
+	e := Entry{Level: Warning, Message: "careful"}
+
+	if actual := (&Console{ColorMode: ColorNever}).Format(e); strings.Contains(actual, "\033[") {
+		t.Errorf("Expected no colours, but got:\n%q", actual)
+	}
+
+	palette := Palette{Warning: {Severity: "<sev>", Text: "<text>"}}
+	actual := (&Console{ColorMode: ColorAlways, Palette: palette}).Format(e)
+	if !strings.Contains(actual, "<sev>[WRN]<text> careful") || !strings.HasSuffix(actual, "\033[0m") {
+		t.Errorf("Expected the colours of the palette, but got:\n%q", actual)
+	}
+}
+
+func Test_Console_ColorAuto_HonoursEnvironment(t *testing.T) {
+	for _, key := range []string{"NO_COLOR", "FORCE_COLOR"} {
+		if value, ok := os.LookupEnv(key); ok {
+			defer os.Setenv(key, value)
+		} else {
+			defer os.Unsetenv(key)
+		}
+		os.Unsetenv(key)
+	}
+
+	file, err := ioutil.TempFile(t.TempDir(), "console")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	e := Entry{Level: Info, Message: "auto"}
+
+	console := &Console{Output: file}
+	if actual := console.Format(e); strings.Contains(actual, "\033[") {
+		t.Errorf("Expected no colours when the output is not a terminal, but got:\n%q", actual)
+	}
+
+	os.Setenv("FORCE_COLOR", "1")
+	if actual := console.Format(e); strings.Contains(actual, "\033[") {
+		t.Errorf("Expected the colour decision to be cached, but got:\n%q", actual)
+	}
+	if actual := (&Console{Output: file}).Format(e); !strings.Contains(actual, "\033[") {
+		t.Errorf("Expected colours when FORCE_COLOR is set, but got:\n%q", actual)
+	}
+
+	os.Setenv("NO_COLOR", "1")
+	if actual := (&Console{Output: file}).Format(e); strings.Contains(actual, "\033[") {
+		t.Errorf("Expected no colours when NO_COLOR is set, but got:\n%q", actual)
+	}
+}
+
+func Test_Stackdriver_FieldsWithReservedKeys_DoNotOverrideEntry(t *testing.T) {
+	stackdriver := Stackdriver{}
+	e := event{level: Error, message: "real"}.
+		Str("severity", "DEBUG").
+		Str("message", "fake").
+		Str("logging.googleapis.com/trace", "other").
+		Str("user", "sue").(event).
+		entry(time.Time{})
+
+	expected := "{\"severity\":\"ERROR\",\"message\":\"real\",\"fields.severity\":\"DEBUG\",\"fields.message\":\"fake\",\"fields.logging.googleapis.com/trace\":\"other\",\"user\":\"sue\"}"
+	if actual := stackdriver.Format(e); actual != expected {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
+	}
+}
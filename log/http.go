@@ -0,0 +1,79 @@
+package log
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stripPort removes the port from a host:port address.
+// Addresses without a port are returned unchanged apart from IPv6 brackets.
+func stripPort(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+}
+
+// serverIP returns the IP address of the connection's local address.
+func serverIP(req *http.Request) string {
+	if addr, ok := req.Context().Value(http.LocalAddrContextKey).(net.Addr); ok && addr != nil {
+		return stripPort(addr.String())
+	}
+	return ""
+}
+
+// Headers which reverse proxies append the address of their client to.
+const (
+	// ForwardedHeader is the standard Forwarded header (RFC 7239).
+	ForwardedHeader = "Forwarded"
+	// XForwardedForHeader is the X-Forwarded-For header, which most load balancers write,
+	// including the Google Cloud load balancers.
+	XForwardedForHeader = "X-Forwarded-For"
+)
+
+// trustedAddress returns the address which has been appended by the outermost of the
+// given number of trusted proxies, counting from the right. If there are fewer addresses
+// than trusted proxies then the request did not pass all of them and an empty string is returned.
+func trustedAddress(addresses []string, trustedProxies int) string {
+	if trustedProxies < 1 || len(addresses) < trustedProxies {
+		return ""
+	}
+	return stripPort(addresses[len(addresses)-trustedProxies])
+}
+
+// forwardedFor returns the client IP from the value of the given header, which has been
+// appended by the outermost of the given number of trusted proxies.
+// Other headers are ignored, because clients can set them to any value.
+func forwardedFor(header, value string, trustedProxies int) string {
+	if len(value) == 0 {
+		return ""
+	}
+
+	var addresses []string
+	switch header {
+	case ForwardedHeader:
+		for _, element := range strings.Split(value, ",") {
+			address := ""
+			for _, pair := range strings.Split(element, ";") {
+				pair = strings.TrimSpace(pair)
+				if len(pair) > 4 && strings.EqualFold(pair[:4], "for=") {
+					address = strings.Trim(pair[4:], "\"")
+				}
+			}
+			addresses = append(addresses, address)
+		}
+	case XForwardedForHeader:
+		for _, address := range strings.Split(value, ",") {
+			addresses = append(addresses, strings.TrimSpace(address))
+		}
+	}
+	return trustedAddress(addresses, trustedProxies)
+}
+
+// formatLatency formats a duration in the Google Cloud duration format, e.g. "3.5s".
+func formatLatency(latency time.Duration) string {
+	return strconv.FormatFloat(latency.Seconds(), 'f', -1, 64) + "s"
+}
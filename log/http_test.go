@@ -0,0 +1,100 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_SetHTTPRequest_StripsPortsAndSetsServerIP(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://example.org/", nil)
+	req.RemoteAddr = "[2001:db8::1]:51234"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+	localAddr := &net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 8080}
+	req = req.WithContext(context.WithValue(req.Context(), http.LocalAddrContextKey, localAddr))
+
+	httpReq := event{}.SetHTTPRequest(req).(event).httpRequest
+	if httpReq.RemoteIP != "2001:db8::1" || httpReq.ServerIP != "10.0.0.2" {
+		t.Errorf("Unexpected remote IP %s or server IP %s", httpReq.RemoteIP, httpReq.ServerIP)
+	}
+
+	httpReq = *event{}.SetHTTPRequest(req).SetTrustedProxies(1, XForwardedForHeader).(event).entry(time.Time{}).HTTPRequest
+	if httpReq.RemoteIP != "10.0.0.1" {
+		t.Errorf("Expected the remote IP from X-Forwarded-For, but got %s", httpReq.RemoteIP)
+	}
+
+	httpReq = *event{}.SetTrustedProxies(2, "x-forwarded-for").SetHTTPRequest(req).(event).entry(time.Time{}).HTTPRequest
+	if httpReq.RemoteIP != "203.0.113.7" {
+		t.Errorf("Expected the remote IP from X-Forwarded-For, but got %s", httpReq.RemoteIP)
+	}
+}
+
+func Test_SetTrustedProxies_IgnoresSpoofedForwardedHeader(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://example.org/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("Forwarded", "for=6.6.6.6")
+	req.Header.Set("X-Forwarded-For", "203.0.113.9")
+
+	httpReq := *event{}.SetTrustedProxies(1, XForwardedForHeader).SetHTTPRequest(req).(event).entry(time.Time{}).HTTPRequest
+	if httpReq.RemoteIP != "203.0.113.9" {
+		t.Errorf("Expected the remote IP from X-Forwarded-For, but got %s", httpReq.RemoteIP)
+	}
+
+	httpReq = *event{}.SetTrustedProxies(2, XForwardedForHeader).SetHTTPRequest(req).(event).entry(time.Time{}).HTTPRequest
+	if httpReq.RemoteIP != "10.0.0.1" {
+		t.Errorf("Expected the connection address if the request did not pass all proxies, but got %s", httpReq.RemoteIP)
+	}
+}
+
+func Test_ForwardedFor_ReturnsClientIP(t *testing.T) {
+	type testCase struct {
+		Header         string
+		Value          string
+		TrustedProxies int
+		Expected       string
+	}
+
+	testCases := []testCase{
+		{XForwardedForHeader, "", 1, ""},
+		{XForwardedForHeader, "203.0.113.7", 1, "203.0.113.7"},
+		{XForwardedForHeader, " 203.0.113.7:1234 , 10.0.0.1", 1, "10.0.0.1"},
+		{XForwardedForHeader, " 203.0.113.7:1234 , 10.0.0.1", 2, "203.0.113.7"},
+		{XForwardedForHeader, "198.51.100.1, 203.0.113.7, 10.0.0.1", 2, "203.0.113.7"},
+		{XForwardedForHeader, "203.0.113.7", 3, ""},
+		{ForwardedHeader, "for=192.0.2.60;proto=http;by=203.0.113.43", 1, "192.0.2.60"},
+		{ForwardedHeader, "For=\"[2001:db8:cafe::17]:4711\", for=10.0.0.1", 1, "10.0.0.1"},
+		{ForwardedHeader, "For=\"[2001:db8:cafe::17]:4711\", for=10.0.0.1", 2, "2001:db8:cafe::17"},
+		{ForwardedHeader, "for=198.51.100.1, for=192.0.2.60, for=10.0.0.1", 2, "192.0.2.60"},
+		{ForwardedHeader, "proto=https", 1, ""},
+		{ForwardedHeader, "for=192.0.2.60", 2, ""},
+		{"X-Real-Ip", "192.0.2.60", 1, ""},
+	}
+
+	for _, testCase := range testCases {
+		if actual := forwardedFor(testCase.Header, testCase.Value, testCase.TrustedProxies); actual != testCase.Expected {
+			t.Errorf("\nExpected:\n%s,\nActual:\n%s", testCase.Expected, actual)
+		}
+	}
+}
+
+func Test_SetHTTPResponse_FormatsCorrectly(t *testing.T) {
+	e := event{}.
+		SetHTTPResponse(201, 512, 1500*time.Millisecond).
+		SetHTTPCache(true, true, false, 2048).(event)
+
+	httpReq := e.httpRequest
+	if httpReq.Status != 201 || httpReq.ResponseSize != "512" || httpReq.Latency != "1.5s" || httpReq.CacheFillBytes != "2048" {
+		t.Errorf("Unexpected HTTP request: %+v", httpReq)
+	}
+
+	expected, err := json.Marshal(httpReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := appendHTTPRequest(nil, &httpReq); string(actual) != string(expected) {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
+	}
+}
@@ -0,0 +1,127 @@
+package log
+
+import (
+	"sync"
+	"unicode/utf8"
+)
+
+// --------------------------------
+// Buffer pool
+// --------------------------------
+
+// Buffers which grew larger than this are not returned to the pool
+// so that a single huge log entry doesn't pin memory forever.
+const maxPooledBufferSize = 64 * 1024
+
+type buffer struct {
+	bytes []byte
+	keys  []string
+}
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return &buffer{bytes: make([]byte, 0, 1024)}
+	},
+}
+
+func getBuffer() *buffer {
+	buf := bufferPool.Get().(*buffer)
+	buf.bytes = buf.bytes[:0]
+	buf.keys = buf.keys[:0]
+	return buf
+}
+
+func putBuffer(buf *buffer) {
+	if cap(buf.bytes) > maxPooledBufferSize {
+		return
+	}
+	bufferPool.Put(buf)
+}
+
+// sortedKeys returns the keys of a map in ascending order.
+// It uses an insertion sort on the pooled key slice, because
+// labels are small and sort.Strings would allocate.
+func (buf *buffer) sortedKeys(m map[string]string) []string {
+	keys := buf.keys[:0]
+	for k := range m {
+		keys = append(keys, k)
+	}
+	for i := 1; i < len(keys); i++ {
+		for j := i; j > 0 && keys[j] < keys[j-1]; j-- {
+			keys[j], keys[j-1] = keys[j-1], keys[j]
+		}
+	}
+	buf.keys = keys
+	return keys
+}
+
+// --------------------------------
+// JSON encoding
+// --------------------------------
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString appends a quoted and escaped JSON string to dst.
+// It escapes the same characters as encoding/json, including HTML characters.
+func appendJSONString(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			dst = append(dst, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				dst = append(dst, '\\', c)
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, "\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, '\\', 'u', '2', '0', '2', hexDigits[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
+
+// appendJSONKey appends a comma separated JSON key to dst.
+func appendJSONKey(dst []byte, key string) []byte {
+	dst = append(dst, ',')
+	dst = appendJSONString(dst, key)
+	return append(dst, ':')
+}
+
+// appendHex appends the lower case hex representation of src to dst.
+func appendHex(dst []byte, src []byte) []byte {
+	for _, b := range src {
+		dst = append(dst, hexDigits[b>>4], hexDigits[b&0xF])
+	}
+	return dst
+}
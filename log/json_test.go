@@ -0,0 +1,47 @@
+package log
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func Test_AppendJSONString_MatchesEncodingJSON(t *testing.T) {
+	testCases := []string{
+		"",
+		"plain text",
+		"quote \" and backslash \\",
+		"new\nline\r\ttab",
+		"control \x00\x01\x1f characters",
+		"<html> & stuff",
+		"unicode: äöü 日本語 🎉",
+		"line separator \u2028 and paragraph separator \u2029",
+		"invalid \xff utf8",
+	}
+
+	for _, testCase := range testCases {
+		expected, err := json.Marshal(testCase)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := appendJSONString(nil, testCase); string(actual) != string(expected) {
+			t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
+		}
+	}
+}
+
+func Test_Buffer_SortedKeys_ReturnsAscendingKeys(t *testing.T) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	keys := buf.sortedKeys(map[string]string{"c": "", "a": "", "B": "", "b": ""})
+	expected := []string{"B", "a", "b", "c"}
+
+	if len(keys) != len(expected) {
+		t.Fatalf("\nExpected:\n%v,\nActual:\n%v", expected, keys)
+	}
+	for i := range expected {
+		if keys[i] != expected[i] {
+			t.Errorf("\nExpected:\n%v,\nActual:\n%v", expected, keys)
+		}
+	}
+}
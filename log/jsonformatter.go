@@ -0,0 +1,221 @@
+package log
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeEncoding decides how the JSON formatter writes timestamps.
+type TimeEncoding int
+
+const (
+	// TimeRFC3339Nano writes timestamps as RFC3339 strings with nanoseconds.
+	TimeRFC3339Nano TimeEncoding = iota
+	// TimeRFC3339 writes timestamps as RFC3339 strings with seconds.
+	TimeRFC3339
+	// TimeUnixMillis writes timestamps as the number of milliseconds since the Unix epoch.
+	TimeUnixMillis
+	// TimeUnixNanos writes timestamps as the number of nanoseconds since the Unix epoch.
+	TimeUnixNanos
+)
+
+// JSONKeys are the property names which the JSON formatter writes.
+// An empty key falls back to its default and a key of "-" omits the property.
+type JSONKeys struct {
+	Time           string // default: time
+	Level          string // default: level
+	Message        string // default: msg
+	Error          string // default: error
+	Stack          string // default: stack
+	TraceID        string // default: trace_id
+	SpanID         string // default: span_id
+	TraceSampled   string // default: trace_sampled
+	Service        string // default: service
+	Version        string // default: version
+	SourceLocation string // default: caller
+	Labels         string // default: labels
+	HTTPRequest    string // default: http
+	Data           string // default: data
+}
+
+func jsonKey(key, defaultKey string) string {
+	if len(key) == 0 {
+		return defaultKey
+	}
+	return key
+}
+
+// withDefaults returns a copy of the keys where empty keys are replaced with their defaults.
+func (k JSONKeys) withDefaults() JSONKeys {
+	return JSONKeys{
+		Time:           jsonKey(k.Time, "time"),
+		Level:          jsonKey(k.Level, "level"),
+		Message:        jsonKey(k.Message, "msg"),
+		Error:          jsonKey(k.Error, "error"),
+		Stack:          jsonKey(k.Stack, "stack"),
+		TraceID:        jsonKey(k.TraceID, "trace_id"),
+		SpanID:         jsonKey(k.SpanID, "span_id"),
+		TraceSampled:   jsonKey(k.TraceSampled, "trace_sampled"),
+		Service:        jsonKey(k.Service, "service"),
+		Version:        jsonKey(k.Version, "version"),
+		SourceLocation: jsonKey(k.SourceLocation, "caller"),
+		Labels:         jsonKey(k.Labels, "labels"),
+		HTTPRequest:    jsonKey(k.HTTPRequest, "http"),
+		Data:           jsonKey(k.Data, "data"),
+	}
+}
+
+// isReserved checks if a key is one of the property names.
+func (k JSONKeys) isReserved(key string) bool {
+	switch key {
+	case k.Time, k.Level, k.Message, k.Error, k.Stack, k.TraceID, k.SpanID, k.TraceSampled,
+		k.Service, k.Version, k.SourceLocation, k.Labels, k.HTTPRequest, k.Data:
+		return key != "-"
+	}
+	return false
+}
+
+// JSON formats an event into a JSON object with configurable property names,
+// level names and time encoding, e.g. for Elastic, Datadog or Loki.
+// Fields are written as top level JSON properties. Fields which clash with
+// one of the Keys are prefixed with "fields.".
+type JSON struct {
+	// Keys overrides the property names.
+	Keys JSONKeys
+	// LevelNames overrides the names of log levels (default: the lower case level name, e.g. "warning").
+	LevelNames map[Level]string
+	// TimeEncoding decides how timestamps are written (default: TimeRFC3339Nano).
+	TimeEncoding TimeEncoding
+}
+
+func (f *JSON) levelName(lvl Level) string {
+	if name, ok := f.LevelNames[lvl]; ok {
+		return name
+	}
+	return strings.ToLower(lvl.String())
+}
+
+func (f *JSON) appendTime(dst []byte, t time.Time) []byte {
+	switch f.TimeEncoding {
+	case TimeUnixMillis:
+		return strconv.AppendInt(dst, t.UnixNano()/int64(time.Millisecond), 10)
+	case TimeUnixNanos:
+		return strconv.AppendInt(dst, t.UnixNano(), 10)
+	case TimeRFC3339:
+		dst = append(dst, '"')
+		dst = t.UTC().AppendFormat(dst, time.RFC3339)
+		return append(dst, '"')
+	default:
+		dst = append(dst, '"')
+		dst = t.UTC().AppendFormat(dst, time.RFC3339Nano)
+		return append(dst, '"')
+	}
+}
+
+// Format formats a log entry into a JSON object.
+func (f *JSON) Format(e Entry) string {
+	keys := f.Keys.withDefaults()
+	buf := getBuffer()
+	b := append(buf.bytes, '{')
+
+	writeKey := func(key string) {
+		if len(b) > 1 {
+			b = append(b, ',')
+		}
+		b = appendJSONString(b, key)
+		b = append(b, ':')
+	}
+
+	// appendKey appends a property name and reports false if the property gets omitted.
+	appendKey := func(key string) bool {
+		if key == "-" {
+			return false
+		}
+		writeKey(key)
+		return true
+	}
+
+	if !e.Timestamp.IsZero() && appendKey(keys.Time) {
+		b = f.appendTime(b, e.Timestamp)
+	}
+	if appendKey(keys.Level) {
+		b = appendJSONString(b, f.levelName(e.Level))
+	}
+	if appendKey(keys.Message) {
+		b = appendJSONString(b, e.Message)
+	}
+
+	if e.Error != nil {
+		if appendKey(keys.Error) {
+			b = appendJSONString(b, e.Error.Error())
+		}
+		if stack := e.StackTrace(); len(stack) > 0 && appendKey(keys.Stack) {
+			b = appendJSONString(b, stack)
+		}
+	}
+
+	if e.TraceID.IsValid() {
+		if appendKey(keys.TraceID) {
+			b = append(b, '"')
+			b = appendHex(b, e.TraceID[:])
+			b = append(b, '"')
+		}
+		if e.SpanID.IsValid() && appendKey(keys.SpanID) {
+			b = append(b, '"')
+			b = appendHex(b, e.SpanID[:])
+			b = append(b, '"')
+		}
+		if appendKey(keys.TraceSampled) {
+			b = strconv.AppendBool(b, e.TraceSampled)
+		}
+	}
+
+	if len(e.ServiceName) > 0 && appendKey(keys.Service) {
+		b = appendJSONString(b, e.ServiceName)
+	}
+	if len(e.ServiceVersion) > 0 && appendKey(keys.Version) {
+		b = appendJSONString(b, e.ServiceVersion)
+	}
+	if e.SourceLocation != nil && appendKey(keys.SourceLocation) {
+		b = append(b, `{"file":`...)
+		b = appendJSONString(b, e.SourceLocation.File)
+		b = append(b, `,"line":`...)
+		b = strconv.AppendInt(b, int64(e.SourceLocation.Line), 10)
+		b = append(b, `,"function":`...)
+		b = appendJSONString(b, e.SourceLocation.Function)
+		b = append(b, '}')
+	}
+
+	if len(e.Labels) > 0 && appendKey(keys.Labels) {
+		b = append(b, '{')
+		for i, key := range buf.sortedKeys(e.Labels) {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = appendJSONString(b, key)
+			b = append(b, ':')
+			b = appendJSONString(b, e.Labels[key])
+		}
+		b = append(b, '}')
+	}
+
+	if e.HasHTTPRequest() && appendKey(keys.HTTPRequest) {
+		b = appendHTTPRequest(b, e.HTTPRequest)
+	}
+
+	for _, field := range e.Fields {
+		writeKey(fieldKey(field.Key, keys.isReserved))
+		b = appendField(b, field)
+	}
+
+	if e.Data != nil && appendKey(keys.Data) {
+		b = appendJSONValue(b, e.Data)
+	}
+
+	b = append(b, '}')
+	output := string(b)
+	buf.bytes = b
+	putBuffer(buf)
+	return output
+}
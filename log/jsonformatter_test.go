@@ -0,0 +1,94 @@
+package log
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/dusted-go/diagnostic/trace"
+)
+
+func Test_JSON_WithDefaults_FormatsCorrectly(t *testing.T) {
+	formatter := JSON{}
+
+	sc, err := trace.ParseTraceparent("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := event{level: Notice, message: "created"}.
+		SetSpanContext(sc).
+		SetServiceName("api").
+		SetServiceVersion("v2").
+		AddLabel("team", "pets").
+		Int("id", 7).
+		SetData(Address{HouseNumber: 3, Street: "x", Postcode: "Y"}).(event).
+		entry(time.Date(2021, 6, 1, 10, 30, 0, 123456789, time.UTC))
+
+	expected := "{\"time\":\"2021-06-01T10:30:00.123456789Z\",\"level\":\"notice\",\"msg\":\"created\",\"trace_id\":\"0af7651916cd43dd8448eb211c80319c\",\"span_id\":\"b7ad6b7169203331\",\"trace_sampled\":false,\"service\":\"api\",\"version\":\"v2\",\"labels\":{\"team\":\"pets\"},\"id\":7,\"data\":{\"HouseNumber\":3,\"Street\":\"x\",\"Postcode\":\"Y\"}}"
+	if actual := formatter.Format(e); actual != expected {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
+	}
+}
+
+func Test_JSON_WithCustomKeysLevelsAndTime_FormatsCorrectly(t *testing.T) {
+	timestamp := time.Date(2021, 6, 1, 10, 30, 0, 123456789, time.UTC)
+	e := event{level: Warning, message: "slow"}.Str("-", "kept").(event).entry(timestamp)
+
+	testCases := []struct {
+		Formatter JSON
+		Expected  string
+	}{
+		{
+			JSON{Keys: JSONKeys{Time: "ts", Level: "severity", Message: "message"}, TimeEncoding: TimeUnixMillis},
+			"{\"ts\":1622543400123,\"severity\":\"warning\",\"message\":\"slow\",\"-\":\"kept\"}",
+		},
+		{
+			JSON{Keys: JSONKeys{Time: "-"}, LevelNames: map[Level]string{Warning: "WARN"}},
+			"{\"level\":\"WARN\",\"msg\":\"slow\",\"-\":\"kept\"}",
+		},
+		{
+			JSON{Keys: JSONKeys{Level: "-", Message: "-"}, TimeEncoding: TimeUnixNanos},
+			"{\"time\":1622543400123456789,\"-\":\"kept\"}",
+		},
+		{
+			JSON{TimeEncoding: TimeRFC3339},
+			"{\"time\":\"2021-06-01T10:30:00Z\",\"level\":\"warning\",\"msg\":\"slow\",\"-\":\"kept\"}",
+		},
+	}
+
+	for _, testCase := range testCases {
+		actual := testCase.Formatter.Format(e)
+		if actual != testCase.Expected {
+			t.Errorf("\nExpected:\n%s,\nActual:\n%s", testCase.Expected, actual)
+		}
+		if !json.Valid([]byte(actual)) {
+			t.Errorf("Expected valid JSON, but got: %s", actual)
+		}
+	}
+}
+
+func Test_JSON_FieldsWithReservedKeys_DoNotOverrideEntry(t *testing.T) {
+	formatter := JSON{Keys: JSONKeys{Message: "message"}}
+	e := event{level: Info, message: "real"}.
+		Str("message", "fake").
+		Str("msg", "kept").(event).
+		entry(time.Time{})
+
+	expected := "{\"level\":\"info\",\"message\":\"real\",\"fields.message\":\"fake\",\"msg\":\"kept\"}"
+	if actual := formatter.Format(e); actual != expected {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
+	}
+}
+
+func Test_JSON_WithSourceLocation_WritesLineAsNumber(t *testing.T) {
+	e := Entry{
+		Level:          Info,
+		Message:        "here",
+		SourceLocation: &SourceLocation{File: "main.go", Line: 12, Function: "main.main"},
+	}
+
+	expected := "{\"level\":\"info\",\"msg\":\"here\",\"caller\":{\"file\":\"main.go\",\"line\":12,\"function\":\"main.main\"}}"
+	if actual := (&JSON{}).Format(e); actual != expected {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
+	}
+}
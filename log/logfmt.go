@@ -0,0 +1,145 @@
+package log
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Logfmt formats an event into a single logfmt line of key=value pairs, e.g.
+//
+//	time=2021-06-01T10:30:00Z level=info msg="user signed in" trace_id=0af7651916cd43dd8448eb211c80319c user=42
+//
+// Fields are written after the standard keys, followed by the labels.
+// Fields and labels which clash with a standard key are prefixed with "fields.", e.g. "fields.level".
+type Logfmt struct {
+}
+
+// logfmtNeedsQuotes checks if a value must be quoted to be parsed as a single logfmt value.
+func logfmtNeedsQuotes(value string) bool {
+	if len(value) == 0 {
+		return true
+	}
+	for _, r := range value {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+// appendLogfmtKey appends a key with a leading space, replacing characters which are not allowed in keys.
+func appendLogfmtKey(dst []byte, key string) []byte {
+	if len(dst) > 0 {
+		dst = append(dst, ' ')
+	}
+	if len(key) == 0 {
+		key = "_"
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c <= ' ' || c == '=' || c == '"' || c == 0x7f {
+			c = '_'
+		}
+		dst = append(dst, c)
+	}
+	return append(dst, '=')
+}
+
+func appendLogfmtValue(dst []byte, value string) []byte {
+	if logfmtNeedsQuotes(value) {
+		return strconv.AppendQuote(dst, value)
+	}
+	return append(dst, value...)
+}
+
+func appendLogfmtPair(dst []byte, key, value string) []byte {
+	dst = appendLogfmtKey(dst, key)
+	return appendLogfmtValue(dst, value)
+}
+
+// isLogfmtKey checks if a key is written by the Logfmt formatter.
+func isLogfmtKey(key string) bool {
+	switch key {
+	case "time", "level", "msg", "trace_id", "span_id", "trace_sampled", "service", "version",
+		"caller", "error", "latency", "remote_ip", "data":
+		return true
+	}
+	return strings.HasPrefix(key, "http_")
+}
+
+// Format formats a log entry into a logfmt line.
+func (f *Logfmt) Format(e Entry) string {
+	buf := getBuffer()
+	b := buf.bytes
+
+	if !e.Timestamp.IsZero() {
+		b = appendLogfmtKey(b, "time")
+		b = e.Timestamp.UTC().AppendFormat(b, time.RFC3339Nano)
+	}
+	b = appendLogfmtPair(b, "level", strings.ToLower(e.Level.String()))
+	b = appendLogfmtPair(b, "msg", e.Message)
+
+	if e.TraceID.IsValid() {
+		b = appendLogfmtKey(b, "trace_id")
+		b = appendHex(b, e.TraceID[:])
+		if e.SpanID.IsValid() {
+			b = appendLogfmtKey(b, "span_id")
+			b = appendHex(b, e.SpanID[:])
+		}
+		b = appendLogfmtKey(b, "trace_sampled")
+		b = strconv.AppendBool(b, e.TraceSampled)
+	}
+
+	if len(e.ServiceName) > 0 {
+		b = appendLogfmtPair(b, "service", e.ServiceName)
+	}
+	if len(e.ServiceVersion) > 0 {
+		b = appendLogfmtPair(b, "version", e.ServiceVersion)
+	}
+	if e.SourceLocation != nil {
+		b = appendLogfmtPair(b, "caller", e.SourceLocation.File+":"+strconv.Itoa(e.SourceLocation.Line))
+	}
+
+	if e.Error != nil {
+		b = appendLogfmtPair(b, "error", e.Error.Error())
+	}
+
+	if e.HasHTTPRequest() {
+		req := e.HTTPRequest
+		b = appendLogfmtPair(b, "http_method", req.RequestMethod)
+		b = appendLogfmtPair(b, "http_url", req.RequestURL)
+		if req.Status > 0 {
+			b = appendLogfmtKey(b, "http_status")
+			b = strconv.AppendInt(b, int64(req.Status), 10)
+		}
+		if len(req.Latency) > 0 {
+			b = appendLogfmtPair(b, "latency", req.Latency)
+		}
+		if len(req.RemoteIP) > 0 {
+			b = appendLogfmtPair(b, "remote_ip", req.RemoteIP)
+		}
+	}
+
+	for _, field := range e.Fields {
+		b = appendLogfmtPair(b, fieldKey(field.Key, isLogfmtKey), field.String())
+	}
+
+	for _, key := range buf.sortedKeys(e.Labels) {
+		b = appendLogfmtPair(b, fieldKey(key, isLogfmtKey), e.Labels[key])
+	}
+
+	if e.Data != nil {
+		data := getBuffer()
+		data.bytes = appendJSONValue(data.bytes, e.Data)
+		b = appendLogfmtKey(b, "data")
+		b = strconv.AppendQuote(b, string(data.bytes))
+		putBuffer(data)
+	}
+
+	output := string(b)
+	buf.bytes = b
+	putBuffer(buf)
+	return output
+}
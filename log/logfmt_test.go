@@ -0,0 +1,64 @@
+package log
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dusted-go/diagnostic/trace"
+)
+
+func Test_Logfmt_FormatsCorrectly(t *testing.T) {
+	logfmt := Logfmt{}
+
+	sc, err := trace.ParseTraceparent("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := event{level: Warning, message: "user \"sue\" signed in"}.
+		SetSpanContext(sc).
+		SetServiceName("api").
+		AddLabel("region", "eu west").
+		Str("user", "sue doe").
+		Int("attempt", 2).
+		Str("path", "a=b").
+		Str("multi", "line\nbreak").
+		Str("", "empty key").(event).
+		entry(time.Date(2021, 6, 1, 10, 30, 0, 0, time.UTC))
+
+	expected := "time=2021-06-01T10:30:00Z level=warning msg=\"user \\\"sue\\\" signed in\" trace_id=0af7651916cd43dd8448eb211c80319c span_id=b7ad6b7169203331 trace_sampled=true service=api user=\"sue doe\" attempt=2 path=\"a=b\" multi=\"line\\nbreak\" _=\"empty key\" region=\"eu west\""
+	if actual := logfmt.Format(e); actual != expected {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
+	}
+}
+
+func Test_Logfmt_WithErrorRequestAndData_FormatsCorrectly(t *testing.T) {
+	logfmt := Logfmt{}
+
+	e := event{level: Error}.
+		SetError(errors.New("connection refused")).
+		SetHTTPRequest(&http.Request{Method: "POST", Host: "example.org", RequestURI: "/orders", RemoteAddr: "10.0.0.1:4321"}).
+		SetHTTPResponse(502, 0, 250*time.Millisecond).
+		SetData(Address{HouseNumber: 3, Street: "x", Postcode: "Y"}).(event).
+		entry(time.Time{})
+
+	expected := "level=error msg=\"\" error=\"connection refused\" http_method=POST http_url=example.org/orders http_status=502 latency=0.25s remote_ip=10.0.0.1 data=\"{\\\"HouseNumber\\\":3,\\\"Street\\\":\\\"x\\\",\\\"Postcode\\\":\\\"Y\\\"}\""
+	if actual := logfmt.Format(e); actual != expected {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
+	}
+}
+
+func Test_Logfmt_FieldsWithReservedKeys_DoNotOverrideEntry(t *testing.T) {
+	e := event{level: Info, message: "m"}.
+		Str("level", "spoof").
+		Str("http_status", "200").
+		AddLabel("msg", "other").(event).
+		entry(time.Time{})
+
+	expected := "level=info msg=m fields.level=spoof fields.http_status=200 fields.msg=other"
+	if actual := (&Logfmt{}).Format(e); actual != expected {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
+	}
+}
@@ -0,0 +1,253 @@
+package log
+
+import (
+	"math"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// otelScopeName is the instrumentation scope of all log records written by this package.
+const otelScopeName = "github.com/dusted-go/diagnostic/log"
+
+// OTelSeverityNumber maps a log level onto the severity number of the OpenTelemetry logs data model.
+// See more at: https://opentelemetry.io/docs/specs/otel/logs/data-model/#field-severitynumber
+func OTelSeverityNumber(lvl Level) int {
+	switch lvl {
+	case Debug:
+		return 5
+	case Info:
+		return 9
+	case Notice:
+		return 10
+	case Warning:
+		return 13
+	case Error:
+		return 17
+	case Critical:
+		return 21
+	case Alert:
+		return 22
+	case Emergency:
+		return 23
+	default:
+		return 0
+	}
+}
+
+// OTel formats an event into the OTLP JSON encoding of the OpenTelemetry logs data model.
+// Every entry is written as an ExportLogsServiceRequest with a single LogRecord, which
+// can be read by the OpenTelemetry collector's otlpjsonfile receiver.
+//
+// Fields and labels are written as attributes and the service name and version as resource attributes.
+// Fields and labels which clash with one of these attributes are prefixed with "fields.".
+type OTel struct {
+	// Resource holds additional resource attributes, e.g. "deployment.environment".
+	Resource map[string]string
+}
+
+// Format formats a log entry into an OTLP JSON ExportLogsServiceRequest.
+func (f *OTel) Format(e Entry) string {
+	buf := getBuffer()
+	b := buf.bytes
+
+	b = append(b, `{"resourceLogs":[{"resource":`...)
+	b = appendOTelResource(b, e.ServiceName, e.ServiceVersion, f.Resource, buf)
+	b = append(b, `,"scopeLogs":[{"scope":{"name":"`+otelScopeName+`"},"logRecords":[`...)
+	b = appendOTelLogRecord(b, e, buf)
+	b = append(b, "]}]}]}"...)
+	output := string(b)
+
+	buf.bytes = b
+	putBuffer(buf)
+	return output
+}
+
+func appendOTelStringAttribute(dst []byte, first bool, key, value string) []byte {
+	if !first {
+		dst = append(dst, ',')
+	}
+	dst = append(dst, `{"key":`...)
+	dst = appendJSONString(dst, key)
+	dst = append(dst, `,"value":{"stringValue":`...)
+	dst = appendJSONString(dst, value)
+	return append(dst, "}}"...)
+}
+
+func appendOTelIntAttribute(dst []byte, first bool, key string, value int64) []byte {
+	if !first {
+		dst = append(dst, ',')
+	}
+	dst = append(dst, `{"key":`...)
+	dst = appendJSONString(dst, key)
+	dst = append(dst, `,"value":{"intValue":"`...)
+	dst = strconv.AppendInt(dst, value, 10)
+	return append(dst, `"}}`...)
+}
+
+// appendOTelFieldAttribute appends a field as an attribute with the closest OTLP value type.
+func appendOTelFieldAttribute(dst []byte, first bool, field Field) []byte {
+	switch field.Kind {
+	case IntField, DurationField:
+		return appendOTelIntAttribute(dst, first, field.Key, field.Int)
+	case BoolField:
+		if !first {
+			dst = append(dst, ',')
+		}
+		dst = append(dst, `{"key":`...)
+		dst = appendJSONString(dst, field.Key)
+		dst = append(dst, `,"value":{"boolValue":`...)
+		dst = strconv.AppendBool(dst, field.Int == 1)
+		return append(dst, "}}"...)
+	case FloatField:
+		if math.IsNaN(field.Float) || math.IsInf(field.Float, 0) {
+			return appendOTelStringAttribute(dst, first, field.Key, field.String())
+		}
+		if !first {
+			dst = append(dst, ',')
+		}
+		dst = append(dst, `{"key":`...)
+		dst = appendJSONString(dst, field.Key)
+		dst = append(dst, `,"value":{"doubleValue":`...)
+		dst = strconv.AppendFloat(dst, field.Float, 'g', -1, 64)
+		return append(dst, "}}"...)
+	case AnyField:
+		return appendOTelStringAttribute(dst, first, field.Key, string(appendJSONValue(nil, field.Any)))
+	default:
+		return appendOTelStringAttribute(dst, first, field.Key, field.String())
+	}
+}
+
+// appendOTelResource appends a resource object with the service name, service version and further attributes.
+func appendOTelResource(dst []byte, serviceName, serviceVersion string, attributes map[string]string, buf *buffer) []byte {
+	dst = append(dst, `{"attributes":[`...)
+	first := true
+	if len(serviceName) > 0 {
+		dst = appendOTelStringAttribute(dst, first, "service.name", serviceName)
+		first = false
+	}
+	if len(serviceVersion) > 0 {
+		dst = appendOTelStringAttribute(dst, first, "service.version", serviceVersion)
+		first = false
+	}
+	for _, key := range buf.sortedKeys(attributes) {
+		dst = appendOTelStringAttribute(dst, first, key, attributes[key])
+		first = false
+	}
+	return append(dst, "]}"...)
+}
+
+// isOTelKey checks if a key is an attribute which the OTel formatter writes.
+func isOTelKey(key string) bool {
+	if key == "data" {
+		return true
+	}
+	for _, prefix := range []string{"exception.", "code.", "http.", "url.", "user_agent.", "client.", "network."} {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// appendOTelLogRecord appends the OTLP JSON encoding of a LogRecord.
+// See more at: https://opentelemetry.io/docs/specs/otel/protocol/file-exporter/
+func appendOTelLogRecord(dst []byte, e Entry, buf *buffer) []byte {
+	dst = append(dst, '{')
+	if !e.Timestamp.IsZero() {
+		dst = append(dst, `"timeUnixNano":"`...)
+		dst = strconv.AppendInt(dst, e.Timestamp.UnixNano(), 10)
+		dst = append(dst, `",`...)
+	}
+	dst = append(dst, `"severityNumber":`...)
+	dst = strconv.AppendInt(dst, int64(OTelSeverityNumber(e.Level)), 10)
+	dst = append(dst, `,"severityText":"`...)
+	dst = append(dst, e.Level.String()...)
+	dst = append(dst, `","body":{"stringValue":`...)
+	dst = appendJSONString(dst, e.Message)
+	dst = append(dst, `},"attributes":[`...)
+
+	first := true
+	attribute := func(key, value string) {
+		dst = appendOTelStringAttribute(dst, first, key, value)
+		first = false
+	}
+
+	if e.Error != nil {
+		attribute("exception.message", e.Error.Error())
+		attribute("exception.type", reflect.TypeOf(e.Error).String())
+		if stack := e.StackTrace(); len(stack) > 0 {
+			attribute("exception.stacktrace", stack)
+		}
+	}
+
+	if e.SourceLocation != nil {
+		attribute("code.filepath", e.SourceLocation.File)
+		dst = appendOTelIntAttribute(dst, first, "code.lineno", int64(e.SourceLocation.Line))
+		attribute("code.function", e.SourceLocation.Function)
+	}
+
+	if e.HasHTTPRequest() {
+		req := e.HTTPRequest
+		attribute("http.request.method", req.RequestMethod)
+		if u, err := url.Parse(req.RequestURL); err == nil {
+			// Server requests usually only carry a path, which is not a full URL.
+			if u.IsAbs() && len(u.Host) > 0 {
+				attribute("url.full", req.RequestURL)
+			} else {
+				if len(u.Path) > 0 {
+					attribute("url.path", u.Path)
+				}
+				if len(u.RawQuery) > 0 {
+					attribute("url.query", u.RawQuery)
+				}
+			}
+		}
+		if req.Status != 0 {
+			dst = appendOTelIntAttribute(dst, first, "http.response.status_code", int64(req.Status))
+		}
+		if len(req.UserAgent) > 0 {
+			attribute("user_agent.original", req.UserAgent)
+		}
+		if len(req.RemoteIP) > 0 {
+			attribute("client.address", req.RemoteIP)
+		}
+		if len(req.Protocol) > 0 {
+			attribute("network.protocol.version", strings.TrimPrefix(req.Protocol, "HTTP/"))
+		}
+	}
+
+	for _, key := range buf.sortedKeys(e.Labels) {
+		attribute(fieldKey(key, isOTelKey), e.Labels[key])
+	}
+
+	for _, field := range e.Fields {
+		field.Key = fieldKey(field.Key, isOTelKey)
+		dst = appendOTelFieldAttribute(dst, first, field)
+		first = false
+	}
+
+	if e.Data != nil {
+		attribute("data", string(appendJSONValue(nil, e.Data)))
+	}
+	dst = append(dst, ']')
+
+	if e.TraceID.IsValid() {
+		dst = append(dst, `,"traceId":"`...)
+		dst = appendHex(dst, e.TraceID[:])
+		dst = append(dst, '"')
+		if e.SpanID.IsValid() {
+			dst = append(dst, `,"spanId":"`...)
+			dst = appendHex(dst, e.SpanID[:])
+			dst = append(dst, '"')
+		}
+		dst = append(dst, `,"flags":`...)
+		if e.TraceSampled {
+			dst = append(dst, '1')
+		} else {
+			dst = append(dst, '0')
+		}
+	}
+	return append(dst, '}')
+}
@@ -0,0 +1,185 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dusted-go/diagnostic/trace"
+)
+
+func Test_OTel_FormatsCorrectly(t *testing.T) {
+	otel := OTel{Resource: map[string]string{"deployment.environment": "prod"}}
+
+	sc, err := trace.ParseTraceparent("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := event{level: Warning, message: "slow request"}.
+		SetSpanContext(sc).
+		SetServiceName("api").
+		SetServiceVersion("v3").
+		AddLabel("team", "pets").
+		Int("attempt", 2).
+		Bool("cached", false).
+		Float("ratio", 0.5).
+		Str("user", "sue").(event).
+		entry(time.Unix(1622543400, 5).UTC())
+
+	expected := "{\"resourceLogs\":[{\"resource\":{\"attributes\":[" +
+		"{\"key\":\"service.name\",\"value\":{\"stringValue\":\"api\"}}," +
+		"{\"key\":\"service.version\",\"value\":{\"stringValue\":\"v3\"}}," +
+		"{\"key\":\"deployment.environment\",\"value\":{\"stringValue\":\"prod\"}}]}," +
+		"\"scopeLogs\":[{\"scope\":{\"name\":\"github.com/dusted-go/diagnostic/log\"},\"logRecords\":[{" +
+		"\"timeUnixNano\":\"1622543400000000005\",\"severityNumber\":13,\"severityText\":\"WARNING\",\"body\":{\"stringValue\":\"slow request\"},\"attributes\":[" +
+		"{\"key\":\"team\",\"value\":{\"stringValue\":\"pets\"}}," +
+		"{\"key\":\"attempt\",\"value\":{\"intValue\":\"2\"}}," +
+		"{\"key\":\"cached\",\"value\":{\"boolValue\":false}}," +
+		"{\"key\":\"ratio\",\"value\":{\"doubleValue\":0.5}}," +
+		"{\"key\":\"user\",\"value\":{\"stringValue\":\"sue\"}}]," +
+		"\"traceId\":\"0af7651916cd43dd8448eb211c80319c\",\"spanId\":\"b7ad6b7169203331\",\"flags\":1}]}]}]}"
+	if actual := otel.Format(e); actual != expected {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
+	}
+}
+
+func Test_AppendOTelLogRecord_HTTPRequestAndReservedKeys(t *testing.T) {
+	e := Entry{
+		Level:   Info,
+		Message: "served",
+		Labels:  map[string]string{"exception.message": "spoof"},
+		Fields:  []Field{{Key: "url.path", Kind: StringField, Str: "/other"}},
+		HTTPRequest: &HTTPRequest{
+			RequestMethod: "GET",
+			RequestURL:    "/foo?x=1",
+			Protocol:      "HTTP/1.1",
+		},
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+	expected := "{\"severityNumber\":9,\"severityText\":\"INFO\",\"body\":{\"stringValue\":\"served\"},\"attributes\":[" +
+		"{\"key\":\"http.request.method\",\"value\":{\"stringValue\":\"GET\"}}," +
+		"{\"key\":\"url.path\",\"value\":{\"stringValue\":\"/foo\"}}," +
+		"{\"key\":\"url.query\",\"value\":{\"stringValue\":\"x=1\"}}," +
+		"{\"key\":\"network.protocol.version\",\"value\":{\"stringValue\":\"1.1\"}}," +
+		"{\"key\":\"fields.exception.message\",\"value\":{\"stringValue\":\"spoof\"}}," +
+		"{\"key\":\"fields.url.path\",\"value\":{\"stringValue\":\"/other\"}}]}"
+	if actual := string(appendOTelLogRecord(nil, e, buf)); actual != expected {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
+	}
+
+	e.HTTPRequest = &HTTPRequest{RequestURL: "https://example.org/foo"}
+	expected = "{\"key\":\"url.full\",\"value\":{\"stringValue\":\"https://example.org/foo\"}}"
+	if actual := string(appendOTelLogRecord(nil, e, buf)); !strings.Contains(actual, expected) {
+		t.Errorf("Expected url.full for an absolute URL, but got:\n%s", actual)
+	}
+}
+
+func Test_OTelSeverityNumber(t *testing.T) {
+	expected := map[Level]int{Default: 0, Debug: 5, Info: 9, Notice: 10, Warning: 13, Error: 17, Critical: 21, Alert: 22, Emergency: 23}
+	for lvl, number := range expected {
+		if actual := OTelSeverityNumber(lvl); actual != number {
+			t.Errorf("Expected %s to map to %d, but got %d.", lvl, number, actual)
+		}
+	}
+}
+
+type otlpRequest struct {
+	ResourceLogs []struct {
+		Resource struct {
+			Attributes []map[string]interface{} `json:"attributes"`
+		} `json:"resource"`
+		ScopeLogs []struct {
+			LogRecords []map[string]interface{} `json:"logRecords"`
+		} `json:"scopeLogs"`
+	} `json:"resourceLogs"`
+}
+
+type fakeCollector struct {
+	mutex    sync.Mutex
+	failures int
+	headers  []http.Header
+	requests []otlpRequest
+}
+
+func (f *fakeCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.failures > 0 {
+		f.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	body, _ := ioutil.ReadAll(r.Body)
+	req := otlpRequest{}
+	if r.URL.Path != "/v1/logs" || json.Unmarshal(body, &req) != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.headers = append(f.headers, r.Header)
+	f.requests = append(f.requests, req)
+	_, _ = w.Write([]byte("{}"))
+}
+
+func Test_OTLPExporter_Close_WritesBatchGroupedByService(t *testing.T) {
+	fake := &fakeCollector{failures: 1}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	exporter := NewOTLPExporter(server.Client(), OTLPOptions{
+		Endpoint:      server.URL + "/v1/logs",
+		Headers:       map[string]string{"Authorization": "Bearer token"},
+		FlushInterval: time.Hour,
+		RetryBackoff:  time.Millisecond,
+	})
+
+	api := New(nil, &OTel{}, exporter, Debug).SetServiceName("api")
+	worker := New(nil, &OTel{}, exporter, Debug).SetServiceName("worker")
+	api.Info().Msg("one")
+	worker.Error().SetError(errors.New("boom")).Msg("two")
+	api.Info().Msg("three")
+	exporter.Export("plain text")
+
+	if err := exporter.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.requests) != 1 {
+		t.Fatalf("Expected 1 request, but got %d.", len(fake.requests))
+	}
+	if fake.headers[0].Get("Authorization") != "Bearer token" || fake.headers[0].Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected headers: %v", fake.headers[0])
+	}
+
+	resourceLogs := fake.requests[0].ResourceLogs
+	if len(resourceLogs) != 3 {
+		t.Fatalf("Expected 3 resources, but got %d.", len(resourceLogs))
+	}
+
+	apiRecords := resourceLogs[0].ScopeLogs[0].LogRecords
+	if len(apiRecords) != 2 || apiRecords[0]["body"].(map[string]interface{})["stringValue"] != "one" || apiRecords[1]["body"].(map[string]interface{})["stringValue"] != "three" {
+		t.Errorf("Unexpected log records of the api service: %+v", apiRecords)
+	}
+	if resourceLogs[0].Resource.Attributes[0]["value"].(map[string]interface{})["stringValue"] != "api" {
+		t.Errorf("Unexpected resource: %+v", resourceLogs[0].Resource)
+	}
+
+	workerRecords := resourceLogs[1].ScopeLogs[0].LogRecords
+	if len(workerRecords) != 1 || workerRecords[0]["severityNumber"] != float64(17) {
+		t.Errorf("Unexpected log records of the worker service: %+v", workerRecords)
+	}
+
+	if len(resourceLogs[2].Resource.Attributes) != 0 || resourceLogs[2].ScopeLogs[0].LogRecords[0]["body"].(map[string]interface{})["stringValue"] != "plain text" {
+		t.Errorf("Unexpected plain text log record: %+v", resourceLogs[2])
+	}
+}
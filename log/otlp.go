@@ -0,0 +1,169 @@
+package log
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/dusted-go/diagnostic/internal/batch"
+)
+
+// DefaultOTLPEndpoint is the URL of the OTLP/HTTP logs endpoint of a local OpenTelemetry collector.
+const DefaultOTLPEndpoint = "http://localhost:4318/v1/logs"
+
+// OTLPOptions configures the endpoint, batching and retry behaviour of an OTLPExporter.
+// Zero values are replaced with sensible defaults.
+type OTLPOptions struct {
+	// Endpoint is the URL of the collector's logs endpoint (default: DefaultOTLPEndpoint).
+	Endpoint string
+	// Headers are added to every request, e.g. for authentication.
+	Headers map[string]string
+	// Resource holds additional resource attributes, e.g. "deployment.environment".
+	Resource map[string]string
+	// BatchSize is the number of log records which triggers a write (default: 100).
+	BatchSize int
+	// MaxBufferedEntries is the maximum number of log records waiting to be written.
+	// Further log records are dropped until the buffer has been written (default: 10 * BatchSize).
+	MaxBufferedEntries int
+	// FlushInterval is the maximum time a log record waits before it gets written (default: 5s).
+	FlushInterval time.Duration
+	// MaxRetries is the number of times a failed write gets retried (default: 3, negative disables retries).
+	MaxRetries int
+	// RetryBackoff is the wait time before the first retry, which doubles with every further retry (default: 500ms).
+	RetryBackoff time.Duration
+}
+
+type otlpRecord struct {
+	serviceName    string
+	serviceVersion string
+	logRecord      []byte
+}
+
+// OTLPExporter writes log entries as OTLP/HTTP JSON batches to an OpenTelemetry collector.
+type OTLPExporter struct {
+	resource map[string]string
+	request  batch.Request
+	batcher  *batch.Batcher
+}
+
+// NewOTLPExporter creates a new OTLPExporter which writes to the collector at the configured endpoint.
+func NewOTLPExporter(client *http.Client, options OTLPOptions) *OTLPExporter {
+	if len(options.Endpoint) == 0 {
+		options.Endpoint = DefaultOTLPEndpoint
+	}
+	header := http.Header{}
+	for key, value := range options.Headers {
+		header.Set(key, value)
+	}
+
+	e := &OTLPExporter{
+		resource: options.Resource,
+		request: batch.Request{
+			Client:    client,
+			Name:      "OTLP endpoint",
+			URL:       options.Endpoint,
+			Header:    header,
+			Retryable: otlpRetryable,
+		},
+	}
+	e.batcher = batch.New(
+		batch.Options{
+			BatchSize:     options.BatchSize,
+			MaxBuffered:   options.MaxBufferedEntries,
+			FlushInterval: options.FlushInterval,
+			MaxRetries:    options.MaxRetries,
+			RetryBackoff:  options.RetryBackoff,
+		},
+		e.send,
+		"Failed to write log records to the OpenTelemetry collector")
+	return e
+}
+
+// otlpRetryable reports if a failed request can be retried.
+// See more at: https://opentelemetry.io/docs/specs/otlp/#failures-1
+func otlpRetryable(status int) bool {
+	return status == http.StatusTooManyRequests ||
+		status == http.StatusBadGateway ||
+		status == http.StatusServiceUnavailable ||
+		status == http.StatusGatewayTimeout
+}
+
+// Export adds a log message of an unknown level as the body of a log record to the current batch.
+func (e *OTLPExporter) Export(output string) {
+	b := append([]byte(nil), `{"severityNumber":0,"body":{"stringValue":`...)
+	b = appendJSONString(b, output)
+	b = append(b, "}}"...)
+	e.batcher.Add(otlpRecord{logRecord: b})
+}
+
+// ExportEntry adds a log entry to the current batch.
+// The formatted output is ignored, because the entry gets mapped onto the OpenTelemetry LogRecord model.
+func (e *OTLPExporter) ExportEntry(entry Entry, _ string) {
+	buf := getBuffer()
+	logRecord := appendOTelLogRecord(nil, entry, buf)
+	putBuffer(buf)
+
+	e.batcher.Add(otlpRecord{
+		serviceName:    entry.ServiceName,
+		serviceVersion: entry.ServiceVersion,
+		logRecord:      logRecord,
+	})
+}
+
+// Dropped returns the number of log records which have been discarded due to a full buffer.
+func (e *OTLPExporter) Dropped() uint64 {
+	return e.batcher.Dropped()
+}
+
+// Flush writes all buffered log records in batches to the collector.
+// Log records of a batch which could not be written after all retries are discarded.
+func (e *OTLPExporter) Flush(ctx context.Context) error {
+	return e.batcher.Flush(ctx)
+}
+
+// Close stops the background flushing and writes all remaining log records.
+// Log entries which get exported after Close are written immediately.
+func (e *OTLPExporter) Close(ctx context.Context) error {
+	return e.batcher.Close(ctx)
+}
+
+// requestBody returns the ExportLogsServiceRequest of a batch.
+// Log records of the same service are grouped into one resource.
+func (e *OTLPExporter) requestBody(items []interface{}) []byte {
+	type service struct{ name, version string }
+	var services []service
+	records := make(map[service][][]byte)
+	for _, item := range items {
+		r := item.(otlpRecord)
+		s := service{r.serviceName, r.serviceVersion}
+		if _, ok := records[s]; !ok {
+			services = append(services, s)
+		}
+		records[s] = append(records[s], r.logRecord)
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	body := append([]byte(nil), `{"resourceLogs":[`...)
+	for i, s := range services {
+		if i > 0 {
+			body = append(body, ',')
+		}
+		body = append(body, `{"resource":`...)
+		body = appendOTelResource(body, s.name, s.version, e.resource, buf)
+		body = append(body, `,"scopeLogs":[{"scope":{"name":"`+otelScopeName+`"},"logRecords":[`...)
+		for j, logRecord := range records[s] {
+			if j > 0 {
+				body = append(body, ',')
+			}
+			body = append(body, logRecord...)
+		}
+		body = append(body, "]}]}"...)
+	}
+	return append(body, "]}"...)
+}
+
+func (e *OTLPExporter) send(ctx context.Context, records []interface{}) error {
+	return e.batcher.Post(ctx, e.request, e.requestBody(records))
+}
@@ -0,0 +1,82 @@
+package log
+
+import (
+	"errors"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const maxStackDepth = 64
+
+// callers returns the program counters of the stack skip frames above the function which calls callers.
+func callers(skip int) []uintptr {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(skip+2, pcs)
+	return pcs[:n]
+}
+
+// stackOf returns the stack trace which an error has recorded itself.
+// It supports errors with a StackTrace method which returns a slice of program counters,
+// such as the errors of github.com/pkg/errors.
+func stackOf(err error) []uintptr {
+	v := reflect.ValueOf(err)
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil
+	}
+	m := v.MethodByName("StackTrace")
+	if !m.IsValid() {
+		return nil
+	}
+	t := m.Type()
+	if t.NumIn() != 0 || t.NumOut() != 1 || t.Out(0).Kind() != reflect.Slice || t.Out(0).Elem().Kind() != reflect.Uintptr {
+		return nil
+	}
+
+	frames := m.Call(nil)[0]
+	pcs := make([]uintptr, frames.Len())
+	for i := range pcs {
+		pcs[i] = uintptr(frames.Index(i).Uint())
+	}
+	return pcs
+}
+
+// errorStack returns the stack trace of the innermost error in the chain which has recorded one.
+func errorStack(err error) []uintptr {
+	var stack []uintptr
+	for err != nil {
+		if s := stackOf(err); len(s) > 0 {
+			stack = s
+		}
+		err = errors.Unwrap(err)
+	}
+	return stack
+}
+
+// formatStack formats program counters in the layout of a Go panic,
+// which is understood by Google Cloud Error Reporting.
+func formatStack(pcs []uintptr) string {
+	if len(pcs) == 0 {
+		return ""
+	}
+
+	var str strings.Builder
+	str.WriteString("goroutine 1 [running]:\n")
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if len(frame.Function) > 0 {
+			str.WriteString(frame.Function)
+			str.WriteString("(...)\n\t")
+			str.WriteString(frame.File)
+			str.WriteString(":")
+			str.WriteString(strconv.Itoa(frame.Line))
+			str.WriteString("\n")
+		}
+		if !more {
+			break
+		}
+	}
+	return str.String()
+}
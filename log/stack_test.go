@@ -0,0 +1,85 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+type frame uintptr
+
+type stackError struct {
+	frames []frame
+}
+
+func (e *stackError) Error() string {
+	return "stack error"
+}
+
+func (e *stackError) StackTrace() []frame {
+	return e.frames
+}
+
+func newStackError() error {
+	pcs := callers(0)
+	frames := make([]frame, len(pcs))
+	for i, pc := range pcs {
+		frames[i] = frame(pc)
+	}
+	return &stackError{frames: frames}
+}
+
+func Test_ErrorStack_UsesStackOfWrappedError(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", newStackError())
+
+	lines := strings.Split(formatStack(errorStack(err)), "\n")
+	if len(lines) < 3 || lines[0] != "goroutine 1 [running]:" || !strings.HasSuffix(lines[1], "/log.newStackError(...)") {
+		t.Errorf("Expected the stack trace to start at newStackError, but got:\n%s", strings.Join(lines, "\n"))
+	}
+
+	if errorStack(errors.New("plain")) != nil {
+		t.Error("Expected no stack trace for an error without one.")
+	}
+	if errorStack((*stackError)(nil)) != nil {
+		t.Error("Expected no stack trace for a nil error.")
+	}
+}
+
+func logFailure(e Event) {
+	e.Error().SetError(errors.New("boom")).Msg("failed")
+}
+
+func Test_Event_Error_CapturesStackAtCallSite(t *testing.T) {
+	filter := &recordingFilter{}
+	e := New(filter, &Stackdriver{}, &recordingExporter{}, Debug).SetCallerSkip(1)
+
+	_, file, line, _ := runtime.Caller(0)
+	logFailure(e)
+
+	entry := filter.entries[0]
+	if entry.ReportLocation == nil || entry.ReportLocation.LineNumber != line+1 {
+		t.Errorf("Expected the report location to skip the helper function, but got %+v", entry.ReportLocation)
+	}
+
+	lines := strings.Split(entry.StackTrace(), "\n")
+	if len(lines) < 3 ||
+		!strings.HasSuffix(lines[1], ".Test_Event_Error_CapturesStackAtCallSite(...)") ||
+		lines[2] != fmt.Sprintf("\t%s:%d", file, line+1) {
+		t.Errorf("Expected the stack trace to start at the call site, but got:\n%s", strings.Join(lines, "\n"))
+	}
+}
+
+func Test_Stackdriver_Error_WritesStackTraceOfEntry(t *testing.T) {
+	filter := &recordingFilter{}
+	New(filter, nil, &recordingExporter{}, Debug).Error().SetError(errors.New("boom")).Msg("failed")
+
+	output := (&Stackdriver{}).Format(filter.entries[0])
+	if strings.Contains(output, "runtime/debug") || strings.Contains(output, "log.(*Stackdriver).Format") {
+		t.Errorf("Expected the stack trace to not contain the formatter's frames, but got:\n%s", output)
+	}
+	if !strings.Contains(output, "failed\\n\\nError:\\n\\nboom\\n\\ngoroutine 1 [running]:\\n") {
+		t.Errorf("Expected the message to contain the error and stack trace, but got:\n%s", output)
+	}
+}
@@ -0,0 +1,381 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// --------------------------------
+// Syslog formatter
+// --------------------------------
+
+// Facility is the syslog facility of a log message.
+type Facility int
+
+// Syslog facilities as defined in RFC 5424.
+const (
+	FacilityKern Facility = iota
+	FacilityUser
+	FacilityMail
+	FacilityDaemon
+	FacilityAuth
+	FacilitySyslog
+	FacilityLPR
+	FacilityNews
+	FacilityUUCP
+	FacilityCron
+	FacilityAuthPriv
+	FacilityFTP
+	FacilityNTP
+	FacilityAudit
+	FacilityAlert
+	FacilityClock
+	FacilityLocal0
+	FacilityLocal1
+	FacilityLocal2
+	FacilityLocal3
+	FacilityLocal4
+	FacilityLocal5
+	FacilityLocal6
+	FacilityLocal7
+)
+
+// SyslogSeverity maps a log level onto the severity of RFC 5424.
+// Entries without a log level are mapped onto the informational severity.
+func SyslogSeverity(lvl Level) int {
+	switch lvl {
+	case Emergency:
+		return 0
+	case Alert:
+		return 1
+	case Critical:
+		return 2
+	case Error:
+		return 3
+	case Warning:
+		return 4
+	case Notice:
+		return 5
+	case Debug:
+		return 7
+	default:
+		return 6
+	}
+}
+
+// syslogTimeFormat is RFC3339 with at most 6 fractional digits, as required by RFC 5424.
+const syslogTimeFormat = "2006-01-02T15:04:05.999999Z07:00"
+
+var (
+	hostnameOnce sync.Once
+	hostname     string
+)
+
+func defaultHostname() string {
+	hostnameOnce.Do(func() {
+		hostname, _ = os.Hostname()
+	})
+	return hostname
+}
+
+// Syslog formats an event into a RFC 5424 syslog message.
+// The labels are written as a structured data element and the fields after the message as key=value pairs.
+// Line breaks in the message are escaped as \r and \n, so that every message stays on a single line.
+// See more at: https://datatracker.ietf.org/doc/html/rfc5424
+//
+// The default SD-IDs use the private enterprise number 32473, which RFC 5612 reserves for
+// documentation. They are placeholders which should be replaced with SD-IDs under your own
+// enterprise number, e.g. "labels@12345", if the messages leave your own infrastructure.
+type Syslog struct {
+	// Facility is combined with the log level into the PRI value (default: FacilityUser).
+	// FacilityKern is reserved for the kernel and gets replaced with FacilityUser.
+	Facility Facility
+	// Hostname is the HOSTNAME of the message (default: os.Hostname).
+	Hostname string
+	// AppName is the APP-NAME of messages without a service name.
+	AppName string
+	// MsgID is the MSGID of the message.
+	MsgID string
+	// LabelsID is the SD-ID of the labels' structured data element (default placeholder: labels@32473).
+	LabelsID string
+	// TraceID is the SD-ID of the trace's structured data element (default placeholder: trace@32473).
+	TraceID string
+}
+
+// appendSyslogHeaderField appends a header field of printable US-ASCII characters or the NILVALUE.
+func appendSyslogHeaderField(dst []byte, value string, maxLength int) []byte {
+	dst = append(dst, ' ')
+	if len(value) == 0 {
+		return append(dst, '-')
+	}
+	if len(value) > maxLength {
+		value = value[:maxLength]
+	}
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c < 33 || c > 126 {
+			c = '_'
+		}
+		dst = append(dst, c)
+	}
+	return dst
+}
+
+// appendSDName appends a SD-NAME, which must not contain '=', ' ', ']' or '"'.
+func appendSDName(dst []byte, name string) []byte {
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c < 33 || c > 126 || c == '=' || c == ']' || c == '"' {
+			c = '_'
+		}
+		dst = append(dst, c)
+	}
+	return dst
+}
+
+// appendSDParam appends a structured data parameter with an escaped value.
+func appendSDParam(dst []byte, name, value string) []byte {
+	dst = append(dst, ' ')
+	dst = appendSDName(dst, name)
+	dst = append(dst, `="`...)
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c == '"' || c == '\\' || c == ']' {
+			dst = append(dst, '\\')
+		}
+		dst = append(dst, c)
+	}
+	return append(dst, '"')
+}
+
+// appendSyslogMessage appends the message with escaped line breaks, because
+// newline framed transports would otherwise split it into several messages.
+func appendSyslogMessage(dst []byte, message string) []byte {
+	for i := 0; i < len(message); i++ {
+		switch c := message[i]; c {
+		case '\r':
+			dst = append(dst, `\r`...)
+		case '\n':
+			dst = append(dst, `\n`...)
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
+
+func syslogID(id, defaultID string) string {
+	if len(id) == 0 {
+		return defaultID
+	}
+	return id
+}
+
+// Format formats a log entry into a RFC 5424 syslog message.
+func (f *Syslog) Format(e Entry) string {
+	buf := getBuffer()
+	b := buf.bytes
+
+	facility := f.Facility
+	if facility == FacilityKern {
+		facility = FacilityUser
+	}
+	b = append(b, '<')
+	b = strconv.AppendInt(b, int64(int(facility)*8+SyslogSeverity(e.Level)), 10)
+	b = append(b, ">1 "...)
+
+	if e.Timestamp.IsZero() {
+		b = append(b, '-')
+	} else {
+		b = e.Timestamp.UTC().AppendFormat(b, syslogTimeFormat)
+	}
+
+	host := f.Hostname
+	if len(host) == 0 {
+		host = defaultHostname()
+	}
+	appName := e.ServiceName
+	if len(appName) == 0 {
+		appName = f.AppName
+	}
+	b = appendSyslogHeaderField(b, host, 255)
+	b = appendSyslogHeaderField(b, appName, 48)
+	b = appendSyslogHeaderField(b, strconv.Itoa(os.Getpid()), 128)
+	b = appendSyslogHeaderField(b, f.MsgID, 32)
+
+	b = append(b, ' ')
+	structured := false
+	if len(e.Labels) > 0 {
+		b = append(b, '[')
+		b = appendSDName(b, syslogID(f.LabelsID, "labels@32473"))
+		for _, key := range buf.sortedKeys(e.Labels) {
+			b = appendSDParam(b, key, e.Labels[key])
+		}
+		b = append(b, ']')
+		structured = true
+	}
+	if e.TraceID.IsValid() {
+		b = append(b, '[')
+		b = appendSDName(b, syslogID(f.TraceID, "trace@32473"))
+		b = appendSDParam(b, "trace_id", e.TraceID.String())
+		if e.SpanID.IsValid() {
+			b = appendSDParam(b, "span_id", e.SpanID.String())
+		}
+		b = appendSDParam(b, "trace_sampled", strconv.FormatBool(e.TraceSampled))
+		b = append(b, ']')
+		structured = true
+	}
+	if !structured {
+		b = append(b, '-')
+	}
+
+	// The message and fields are written as logfmt pairs after the message.
+	msg := getBuffer()
+	m := appendSyslogMessage(msg.bytes, e.Message)
+	if len(e.ServiceVersion) > 0 {
+		m = appendLogfmtPair(m, "version", e.ServiceVersion)
+	}
+	if e.Error != nil {
+		m = appendLogfmtPair(m, "error", e.Error.Error())
+	}
+	for _, field := range e.Fields {
+		m = appendLogfmtPair(m, fieldKey(field.Key, isLogfmtKey), field.String())
+	}
+	if len(m) > 0 {
+		b = append(b, ' ')
+		b = append(b, m...)
+	}
+	msg.bytes = m
+	putBuffer(msg)
+
+	output := string(b)
+	buf.bytes = b
+	putBuffer(buf)
+	return output
+}
+
+// --------------------------------
+// Syslog exporter
+// --------------------------------
+
+// SyslogExporter writes log messages to a syslog server over UDP, TCP or a Unix socket.
+// Messages over TCP use the octet counting framing of RFC 6587, messages over a Unix stream
+// socket are terminated by a newline and every other message is written as a single datagram.
+type SyslogExporter struct {
+	network string
+	address string
+	timeout time.Duration
+
+	mutex  sync.Mutex
+	conn   net.Conn
+	closed bool
+}
+
+// NewSyslogExporter creates a new SyslogExporter and connects to the syslog server at the given address.
+// Supported networks are "udp", "tcp", "unix" and "unixgram". An empty network and address
+// connect to the local syslog daemon at /dev/log.
+func NewSyslogExporter(network, address string) (*SyslogExporter, error) {
+	e := &SyslogExporter{network: network, address: address, timeout: 5 * time.Second}
+	if len(network) == 0 && len(address) == 0 {
+		e.address = "/dev/log"
+	}
+	if err := e.connect(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// connect must be called with the mutex held or before the exporter gets shared.
+func (e *SyslogExporter) connect() error {
+	if len(e.network) > 0 {
+		conn, err := net.DialTimeout(e.network, e.address, e.timeout)
+		if err != nil {
+			return fmt.Errorf("error connecting to syslog server: %w", err)
+		}
+		e.conn = conn
+		return nil
+	}
+
+	// The local syslog daemon listens on a datagram or stream socket.
+	var err error
+	for _, network := range []string{"unixgram", "unix"} {
+		var conn net.Conn
+		if conn, err = net.DialTimeout(network, e.address, e.timeout); err == nil {
+			e.network = network
+			e.conn = conn
+			return nil
+		}
+	}
+	return fmt.Errorf("error connecting to local syslog daemon: %w", err)
+}
+
+func (e *SyslogExporter) frame(output string) []byte {
+	switch e.network {
+	case "tcp", "tcp4", "tcp6":
+		b := strconv.AppendInt(nil, int64(len(output)), 10)
+		b = append(b, ' ')
+		return append(b, output...)
+	case "unix":
+		return append([]byte(output), '\n')
+	default:
+		return []byte(output)
+	}
+}
+
+// Export writes a syslog message. A broken connection gets re-established once.
+func (e *SyslogExporter) Export(output string) {
+	if err := e.write(e.frame(output)); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write log message to syslog: %v\n", err)
+	}
+}
+
+func (e *SyslogExporter) write(message []byte) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.closed {
+		return errors.New("syslog exporter has been closed")
+	}
+	if e.conn != nil {
+		_ = e.conn.SetWriteDeadline(time.Now().Add(e.timeout))
+		if _, err := e.conn.Write(message); err == nil {
+			return nil
+		}
+		_ = e.conn.Close()
+		e.conn = nil
+	}
+
+	if err := e.connect(); err != nil {
+		return err
+	}
+	_ = e.conn.SetWriteDeadline(time.Now().Add(e.timeout))
+	_, err := e.conn.Write(message)
+	return err
+}
+
+// Close closes the connection to the syslog server.
+// Log messages which get exported after Close are discarded.
+func (e *SyslogExporter) Close(_ context.Context) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.closed = true
+	if e.conn == nil {
+		return nil
+	}
+	err := e.conn.Close()
+	e.conn = nil
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		return err
+	}
+	return nil
+}
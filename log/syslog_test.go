@@ -0,0 +1,191 @@
+package log
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dusted-go/diagnostic/trace"
+)
+
+func Test_Syslog_FormatsCorrectly(t *testing.T) {
+	syslog := Syslog{Facility: FacilityLocal0, Hostname: "appliance-1", MsgID: "ORDERS"}
+
+	sc, err := trace.ParseTraceparent("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := event{level: Error, message: "order failed"}.
+		SetSpanContext(sc).
+		SetServiceName("shop").
+		SetServiceVersion("1.4.0").
+		AddLabel("team", "check\"out").
+		AddLabel("zone", "a]b").
+		SetError(errors.New("out of stock")).
+		Int("order_id", 7).(event).
+		entry(time.Date(2021, 6, 1, 10, 30, 0, 123456789, time.UTC))
+
+	expected := fmt.Sprintf("<131>1 2021-06-01T10:30:00.123456Z appliance-1 shop %d ORDERS "+
+		"[labels@32473 team=\"check\\\"out\" zone=\"a\\]b\"]"+
+		"[trace@32473 trace_id=\"0af7651916cd43dd8448eb211c80319c\" span_id=\"b7ad6b7169203331\" trace_sampled=\"true\"] "+
+		"order failed version=1.4.0 error=\"out of stock\" order_id=7", os.Getpid())
+	if actual := syslog.Format(e); actual != expected {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
+	}
+}
+
+func Test_Syslog_WithoutOptionalValues_WritesNilValues(t *testing.T) {
+	syslog := Syslog{Hostname: "host name"}
+
+	expected := fmt.Sprintf("<14>1 - host_name - %d - -", os.Getpid())
+	if actual := syslog.Format(Entry{Level: Info}); actual != expected {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
+	}
+}
+
+func Test_Syslog_MessageWithLineBreaks_StaysOnOneLine(t *testing.T) {
+	syslog := Syslog{Hostname: "host"}
+	e := Entry{Level: Info, Message: "first\r\nsecond", Fields: []Field{{Key: "stack", Kind: StringField, Str: "a\nb"}}}
+
+	expected := fmt.Sprintf("<14>1 - host - %d - - first\\r\\nsecond stack=\"a\\nb\"", os.Getpid())
+	if actual := syslog.Format(e); actual != expected {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
+	}
+}
+
+func Test_Syslog_FieldsWithReservedKeys_DoNotOverrideEntry(t *testing.T) {
+	syslog := Syslog{Hostname: "host"}
+	e := Entry{Level: Info, Message: "m", ServiceVersion: "v1", Fields: []Field{{Key: "version", Kind: StringField, Str: "spoof"}}}
+
+	expected := fmt.Sprintf("<14>1 - host - %d - - m version=v1 fields.version=spoof", os.Getpid())
+	if actual := syslog.Format(e); actual != expected {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
+	}
+}
+
+func Test_SyslogSeverity(t *testing.T) {
+	expected := map[Level]int{Emergency: 0, Alert: 1, Critical: 2, Error: 3, Warning: 4, Notice: 5, Info: 6, Debug: 7, Default: 6}
+	for lvl, severity := range expected {
+		if actual := SyslogSeverity(lvl); actual != severity {
+			t.Errorf("Expected %s to map to %d, but got %d.", lvl, severity, actual)
+		}
+	}
+}
+
+func Test_SyslogExporter_UDP_WritesDatagrams(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	exporter, err := NewSyslogExporter("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer exporter.Close(context.Background())
+
+	exporter.Export("<14>1 - - - - - - first")
+	exporter.Export("<14>1 - - - - - - second")
+
+	for _, expected := range []string{"<14>1 - - - - - - first", "<14>1 - - - - - - second"} {
+		buf := make([]byte, 1024)
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := string(buf[:n]); actual != expected {
+			t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
+		}
+	}
+}
+
+func Test_SyslogExporter_TCP_UsesOctetCounting(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			received <- nil
+			return
+		}
+		defer conn.Close()
+
+		var messages []string
+		r := bufio.NewReader(conn)
+		for len(messages) < 2 {
+			length, err := r.ReadString(' ')
+			if err != nil {
+				break
+			}
+			n, _ := strconv.Atoi(length[:len(length)-1])
+			message := make([]byte, n)
+			if _, err := io.ReadFull(r, message); err != nil {
+				break
+			}
+			messages = append(messages, string(message))
+		}
+		received <- messages
+	}()
+
+	exporter, err := NewSyslogExporter("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer exporter.Close(context.Background())
+
+	exporter.Export("<14>1 - - - - - - multi\nline")
+	exporter.Export("<14>1 - - - - - - second")
+
+	select {
+	case messages := <-received:
+		if len(messages) != 2 || messages[0] != "<14>1 - - - - - - multi\nline" || messages[1] != "<14>1 - - - - - - second" {
+			t.Errorf("Unexpected messages: %q", messages)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for syslog messages.")
+	}
+}
+
+func Test_SyslogExporter_Unixgram_WritesDatagrams(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("Unix datagram sockets are not supported: %v", err)
+	}
+	defer conn.Close()
+
+	exporter, err := NewSyslogExporter("unixgram", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exporter.Export("<14>1 - - - - - - local")
+	if err := exporter.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := string(buf[:n]); actual != "<14>1 - - - - - - local" {
+		t.Errorf("Unexpected message: %q", actual)
+	}
+}
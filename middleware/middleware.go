@@ -0,0 +1,125 @@
+// Package middleware provides HTTP middleware which creates request scoped log events.
+package middleware
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/dusted-go/diagnostic/log"
+	"github.com/dusted-go/diagnostic/trace"
+)
+
+// --------------------------------
+// Response recorder
+// --------------------------------
+
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int64
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *responseRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		if r.status == 0 {
+			r.status = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+func (r *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := r.ResponseWriter.(http.Hijacker); ok {
+		if r.status == 0 {
+			r.status = http.StatusSwitchingProtocols
+		}
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("the response writer does not implement http.Hijacker")
+}
+
+// Unwrap returns the original response writer for http.ResponseController.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *responseRecorder) statusCode() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+// --------------------------------
+// Request logger
+// --------------------------------
+
+func levelForStatus(e log.Event, status int) log.Event {
+	switch {
+	case status >= 500:
+		return e.Error()
+	case status >= 400:
+		return e.Warning()
+	default:
+		return e.Info()
+	}
+}
+
+// RequestLogger returns a middleware which reads the trace headers of an incoming request
+// and stores the trace information and a request scoped log event in the request's context.
+// Handlers can retrieve the log event with log.Inherit.
+//
+// After the request has been handled the middleware writes one log entry with the
+// full HTTP request including the response status, response size and latency.
+// Requests without trace headers start a new trace and the trace.DefaultSampler
+// decides if it gets sampled, otherwise the sampling decision of the caller is kept.
+func RequestLogger(base log.Event, propagator trace.Propagator) func(http.Handler) http.Handler {
+	if base == nil {
+		base = log.DefaultEvent
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			sc, ok := propagator.Extract(r.Header)
+			if !ok || !sc.IsValid() {
+				sc = trace.NewSpanContext(sc, r.URL.Path)
+			}
+
+			e := base.
+				SetHTTPRequest(r).
+				SetSpanContext(sc)
+
+			ctx := trace.ContextWithSpanContext(r.Context(), sc)
+			ctx = log.Context(ctx, e)
+
+			rec := &responseRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r.WithContext(ctx))
+
+			status := rec.statusCode()
+			levelForStatus(e, status).
+				SetHTTPResponse(status, rec.size, time.Since(start)).
+				Fmt("%s %s %d", r.Method, r.URL.Path, status)
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/dusted-go/diagnostic/log"
+	"github.com/dusted-go/diagnostic/trace"
+)
+
+type recordingExporter struct {
+	mutex   sync.Mutex
+	outputs []map[string]interface{}
+}
+
+func (e *recordingExporter) Export(output string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	entry := map[string]interface{}{}
+	_ = json.Unmarshal([]byte(output), &entry)
+	e.outputs = append(e.outputs, entry)
+}
+
+func Test_RequestLogger_CreatesRequestScopedEvent(t *testing.T) {
+	exporter := &recordingExporter{}
+	base := log.New(nil, &log.Stackdriver{}, exporter, log.Debug)
+
+	handler := RequestLogger(base, trace.DefaultPropagator)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.Inherit(r.Context()).Info().Msg("inside handler")
+
+		if traceID, ok := trace.TryGetID(r.Context()); !ok || traceID.String() != "0af7651916cd43dd8448eb211c80319c" {
+			t.Errorf("Unexpected trace ID in context: %s", traceID)
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("not found"))
+	}))
+
+	req := httptest.NewRequest("GET", "http://example.org/foo?bar=1", nil)
+	req.Header.Set(trace.TraceparentHeader, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusNotFound || resp.Body.String() != "not found" {
+		t.Errorf("Unexpected response: %d %s", resp.Code, resp.Body.String())
+	}
+
+	if len(exporter.outputs) != 2 {
+		t.Fatalf("Expected 2 log entries, but got %d.", len(exporter.outputs))
+	}
+
+	for _, entry := range exporter.outputs {
+		if entry["logging.googleapis.com/trace"] != "0af7651916cd43dd8448eb211c80319c" || entry["logging.googleapis.com/trace_sampled"] != true {
+			t.Errorf("Unexpected trace ID: %v", entry["logging.googleapis.com/trace"])
+		}
+		httpRequest, ok := entry["httpRequest"].(map[string]interface{})
+		if !ok || httpRequest["requestMethod"] != "GET" || httpRequest["requestUrl"] != "http://example.org/foo?bar=1" {
+			t.Errorf("Unexpected HTTP request: %v", entry["httpRequest"])
+		}
+	}
+
+	requestLog := exporter.outputs[1]
+	httpRequest := requestLog["httpRequest"].(map[string]interface{})
+	if requestLog["severity"] != "WARNING" ||
+		requestLog["message"] != "GET /foo 404" ||
+		httpRequest["status"] != float64(404) ||
+		httpRequest["responseSize"] != "9" ||
+		httpRequest["latency"] == nil {
+		t.Errorf("Unexpected request log entry: %v", requestLog)
+	}
+}
+
+func Test_RequestLogger_StartsNewTraceWithoutHeaders(t *testing.T) {
+	exporter := &recordingExporter{}
+	base := log.New(nil, &log.Stackdriver{}, exporter, log.Debug)
+
+	var sc trace.SpanContext
+	handler := RequestLogger(base, trace.DefaultPropagator)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sc, _ = trace.TryGetSpanContext(r.Context())
+		_, _ = w.Write([]byte("ok"))
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if !sc.IsValid() {
+		t.Errorf("Expected a new valid span context, but got %+v", sc)
+	}
+	if len(exporter.outputs) != 1 || exporter.outputs[0]["severity"] != "INFO" || exporter.outputs[0]["httpRequest"].(map[string]interface{})["status"] != float64(200) {
+		t.Errorf("Unexpected request log entry: %v", exporter.outputs)
+	}
+}
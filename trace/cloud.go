@@ -0,0 +1,66 @@
+package trace
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// CloudTraceContextHeader is the name of the HTTP header which Google Cloud
+// load balancers and front ends use to propagate trace information.
+const CloudTraceContextHeader = "X-Cloud-Trace-Context"
+
+// ParseCloudTraceContext returns a span context from a X-Cloud-Trace-Context
+// header value in the format TRACE_ID/SPAN_ID;o=OPTIONS.
+// The span ID and the options are optional. A span ID of 0 is treated as a missing span ID.
+// The option o=1 marks the trace as sampled.
+// See more at: https://cloud.google.com/trace/docs/setup#force-trace
+func ParseCloudTraceContext(value string) (SpanContext, error) {
+	sc := SpanContext{}
+
+	value = strings.TrimSpace(value)
+	options := ""
+	if i := strings.IndexByte(value, ';'); i >= 0 {
+		value, options = value[:i], value[i+1:]
+	}
+
+	traceValue, spanValue := value, ""
+	if i := strings.IndexByte(value, '/'); i >= 0 {
+		traceValue, spanValue = value[:i], value[i+1:]
+	}
+
+	traceID, err := ParseID(strings.ToLower(traceValue))
+	if err != nil {
+		return sc, err
+	}
+	sc.TraceID = traceID
+
+	if len(spanValue) > 0 && strings.TrimLeft(spanValue, "0") != "" {
+		spanID, err := ParseGoogleCloudSpanID(spanValue)
+		if err != nil {
+			return sc, err
+		}
+		sc.SpanID = spanID
+	}
+
+	if len(options) > 0 {
+		if !strings.HasPrefix(options, "o=") {
+			return sc, errors.New("cannot parse X-Cloud-Trace-Context options because they must start with o=")
+		}
+		o, err := strconv.ParseUint(options[2:], 10, 8)
+		if err != nil {
+			return sc, errors.New("cannot parse X-Cloud-Trace-Context options because they must be a number")
+		}
+		sc.Flags = sc.Flags.WithSampled(o&1 == 1)
+	}
+	return sc, nil
+}
+
+// FormatCloudTraceContext returns the X-Cloud-Trace-Context header value of a span context.
+func FormatCloudTraceContext(sc SpanContext) string {
+	o := "0"
+	if sc.IsSampled() {
+		o = "1"
+	}
+	return sc.TraceID.String() + "/" + strconv.FormatUint(sc.SpanID.Decimal(), 10) + ";o=" + o
+}
@@ -0,0 +1,65 @@
+package trace
+
+import (
+	"testing"
+)
+
+func Test_ParseCloudTraceContext_ValidValues(t *testing.T) {
+	type testCase struct {
+		Value   string
+		TraceID string
+		SpanID  uint64
+		Sampled bool
+	}
+
+	testCases := []testCase{
+		{"105445aa7843bc8bf206b12000100000/2205310701640571284;o=1", "105445aa7843bc8bf206b12000100000", 2205310701640571284, true},
+		{"105445aa7843bc8bf206b12000100000/2205310701640571284;o=0", "105445aa7843bc8bf206b12000100000", 2205310701640571284, false},
+		{"105445AA7843BC8BF206B12000100000/1", "105445aa7843bc8bf206b12000100000", 1, false},
+		{"105445aa7843bc8bf206b12000100000", "105445aa7843bc8bf206b12000100000", 0, false},
+		{"105445aa7843bc8bf206b12000100000;o=1", "105445aa7843bc8bf206b12000100000", 0, true},
+		{"105445aa7843bc8bf206b12000100000/0;o=1", "105445aa7843bc8bf206b12000100000", 0, true},
+		{"105445aa7843bc8bf206b12000100000/0123;o=0", "105445aa7843bc8bf206b12000100000", 123, false},
+	}
+
+	for _, testCase := range testCases {
+		sc, err := ParseCloudTraceContext(testCase.Value)
+		if err != nil {
+			t.Errorf("Failed to parse %s: %v", testCase.Value, err)
+			continue
+		}
+		if sc.TraceID.String() != testCase.TraceID || sc.SpanID.Decimal() != testCase.SpanID || sc.IsSampled() != testCase.Sampled {
+			t.Errorf("Unexpected span context for %s: %+v", testCase.Value, sc)
+		}
+	}
+}
+
+func Test_ParseCloudTraceContext_InvalidValues(t *testing.T) {
+	testCases := []string{
+		"",
+		"abc/123;o=1",
+		"00000000000000000000000000000000/123;o=1",
+		"105445aa7843bc8bf206b12000100000/abc;o=1",
+		"105445aa7843bc8bf206b12000100000/0x1f;o=1",
+		"105445aa7843bc8bf206b12000100000/-1;o=1",
+		"105445aa7843bc8bf206b12000100000/123;x=1",
+		"105445aa7843bc8bf206b12000100000/123;o=yes",
+	}
+
+	for _, testCase := range testCases {
+		if sc, err := ParseCloudTraceContext(testCase); err == nil {
+			t.Errorf("Expected an error for %q, but got %+v", testCase, sc)
+		}
+	}
+}
+
+func Test_FormatCloudTraceContext_RoundTrip(t *testing.T) {
+	value := "105445aa7843bc8bf206b12000100000/2205310701640571284;o=1"
+	sc, err := ParseCloudTraceContext(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := FormatCloudTraceContext(sc); actual != value {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", value, actual)
+	}
+}
@@ -0,0 +1,267 @@
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dusted-go/diagnostic/internal/batch"
+)
+
+// --------------------------------
+// Cloud Trace v2 JSON schema
+// --------------------------------
+
+// See more at: https://cloud.google.com/trace/docs/reference/v2/rest/v2/projects.traces/batchWrite
+
+type truncatableString struct {
+	Value              string `json:"value"`
+	TruncatedByteCount int    `json:"truncatedByteCount"`
+}
+
+type attributeValue struct {
+	StringValue *truncatableString `json:"stringValue,omitempty"`
+	IntValue    *string            `json:"intValue,omitempty"`
+	BoolValue   *bool              `json:"boolValue,omitempty"`
+}
+
+type attributes struct {
+	AttributeMap map[string]attributeValue `json:"attributeMap,omitempty"`
+}
+
+type annotation struct {
+	Description truncatableString `json:"description"`
+	Attributes  *attributes       `json:"attributes,omitempty"`
+}
+
+type timeEvent struct {
+	Time       string     `json:"time"`
+	Annotation annotation `json:"annotation"`
+}
+
+type timeEvents struct {
+	TimeEvent []timeEvent `json:"timeEvent"`
+}
+
+type cloudTraceStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type cloudTraceSpan struct {
+	Name         string            `json:"name"`
+	SpanID       string            `json:"spanId"`
+	ParentSpanID string            `json:"parentSpanId,omitempty"`
+	DisplayName  truncatableString `json:"displayName"`
+	StartTime    string            `json:"startTime"`
+	EndTime      string            `json:"endTime"`
+	Attributes   *attributes       `json:"attributes,omitempty"`
+	TimeEvents   *timeEvents       `json:"timeEvents,omitempty"`
+	Status       *cloudTraceStatus `json:"status,omitempty"`
+}
+
+type batchWriteRequest struct {
+	Spans []cloudTraceSpan `json:"spans"`
+}
+
+// Cloud Trace limits display names to 128 bytes and attribute values to 256 bytes.
+const (
+	maxDisplayNameLength    = 128
+	maxAttributeValueLength = 256
+)
+
+// truncate cuts a value to at most maxLength bytes without splitting a UTF-8 encoded character.
+func truncate(value string, maxLength int) truncatableString {
+	if len(value) <= maxLength {
+		return truncatableString{Value: value}
+	}
+	n := maxLength
+	for n > 0 && !utf8.RuneStart(value[n]) {
+		n--
+	}
+	return truncatableString{Value: value[:n], TruncatedByteCount: len(value) - n}
+}
+
+func toAttributes(m map[string]interface{}) *attributes {
+	if len(m) == 0 {
+		return nil
+	}
+
+	attrs := &attributes{AttributeMap: make(map[string]attributeValue, len(m))}
+	for key, value := range m {
+		var v attributeValue
+		switch typed := value.(type) {
+		case bool:
+			v.BoolValue = &typed
+		case int:
+			s := strconv.FormatInt(int64(typed), 10)
+			v.IntValue = &s
+		case int64:
+			s := strconv.FormatInt(typed, 10)
+			v.IntValue = &s
+		case int32:
+			s := strconv.FormatInt(int64(typed), 10)
+			v.IntValue = &s
+		case string:
+			s := truncate(typed, maxAttributeValueLength)
+			v.StringValue = &s
+		default:
+			s := truncate(fmt.Sprintf("%v", typed), maxAttributeValueLength)
+			v.StringValue = &s
+		}
+		attrs.AttributeMap[key] = v
+	}
+	return attrs
+}
+
+func toCloudTraceSpan(projectID string, span SpanData) cloudTraceSpan {
+	s := cloudTraceSpan{
+		Name:        fmt.Sprintf("projects/%s/traces/%s/spans/%s", projectID, span.SpanContext.TraceID, span.SpanContext.SpanID),
+		SpanID:      span.SpanContext.SpanID.String(),
+		DisplayName: truncate(span.Name, maxDisplayNameLength),
+		StartTime:   span.StartTime.UTC().Format(time.RFC3339Nano),
+		EndTime:     span.EndTime.UTC().Format(time.RFC3339Nano),
+		Attributes:  toAttributes(span.Attributes),
+	}
+
+	if span.ParentSpanID.IsValid() {
+		s.ParentSpanID = span.ParentSpanID.String()
+	}
+
+	if len(span.Events) > 0 {
+		s.TimeEvents = &timeEvents{}
+		for _, e := range span.Events {
+			s.TimeEvents.TimeEvent = append(s.TimeEvents.TimeEvent, timeEvent{
+				Time: e.Time.UTC().Format(time.RFC3339Nano),
+				Annotation: annotation{
+					Description: truncate(e.Name, maxAttributeValueLength),
+					Attributes:  toAttributes(e.Attributes),
+				},
+			})
+		}
+	}
+
+	switch span.Status.Code {
+	case StatusOK:
+		s.Status = &cloudTraceStatus{Code: 0, Message: span.Status.Message}
+	case StatusError:
+		// 2 is the google.rpc.Code for UNKNOWN errors.
+		s.Status = &cloudTraceStatus{Code: 2, Message: span.Status.Message}
+	}
+	return s
+}
+
+// FormatCloudTraceBatch returns the Cloud Trace v2 batchWrite JSON request body for the given spans.
+func FormatCloudTraceBatch(projectID string, spans []SpanData) ([]byte, error) {
+	req := batchWriteRequest{Spans: make([]cloudTraceSpan, 0, len(spans))}
+	for _, span := range spans {
+		req.Spans = append(req.Spans, toCloudTraceSpan(projectID, span))
+	}
+	return json.Marshal(req)
+}
+
+// --------------------------------
+// Cloud Trace exporter
+// --------------------------------
+
+// DefaultCloudTraceEndpoint is the base URL of the Google Cloud Trace v2 API.
+const DefaultCloudTraceEndpoint = "https://cloudtrace.googleapis.com/v2"
+
+// CloudTraceOptions configures the batching and retry behaviour of a CloudTraceExporter.
+// Zero values are replaced with sensible defaults.
+type CloudTraceOptions struct {
+	// Endpoint is the base URL of the Cloud Trace v2 API (default: DefaultCloudTraceEndpoint).
+	Endpoint string
+	// BatchSize is the number of spans which triggers a write (default: 100).
+	BatchSize int
+	// MaxBufferedSpans is the maximum number of spans waiting to be written.
+	// Further spans are dropped until the buffer has been written (default: 10 * BatchSize).
+	MaxBufferedSpans int
+	// FlushInterval is the maximum time a span waits before it gets written (default: 5s).
+	FlushInterval time.Duration
+	// MaxRetries is the number of times a failed write gets retried (default: 3, negative disables retries).
+	MaxRetries int
+	// RetryBackoff is the wait time before the first retry, which doubles with every further retry (default: 500ms).
+	RetryBackoff time.Duration
+}
+
+// CloudTraceExporter writes batches of finished spans to the Cloud Trace v2 batchWrite API.
+//
+// The given HTTP client must authenticate its requests,
+// for example a client from golang.org/x/oauth2/google.DefaultClient.
+type CloudTraceExporter struct {
+	projectID string
+	request   batch.Request
+	batcher   *batch.Batcher
+}
+
+// NewCloudTraceExporter creates a new CloudTraceExporter which writes spans to the given project.
+// An error is returned if the project ID is empty.
+// Spans get written once a batch is full, after the flush interval or on Flush and Close.
+func NewCloudTraceExporter(client *http.Client, projectID string, options CloudTraceOptions) (*CloudTraceExporter, error) {
+	if len(projectID) == 0 {
+		return nil, errors.New("cannot create Cloud Trace exporter because the project ID is empty")
+	}
+	if len(options.Endpoint) == 0 {
+		options.Endpoint = DefaultCloudTraceEndpoint
+	}
+
+	e := &CloudTraceExporter{
+		projectID: projectID,
+		request: batch.Request{
+			Client: client,
+			Name:   "Cloud Trace",
+			URL:    fmt.Sprintf("%s/projects/%s/traces:batchWrite", options.Endpoint, projectID),
+		},
+	}
+	e.batcher = batch.New(
+		batch.Options{
+			BatchSize:     options.BatchSize,
+			MaxBuffered:   options.MaxBufferedSpans,
+			FlushInterval: options.FlushInterval,
+			MaxRetries:    options.MaxRetries,
+			RetryBackoff:  options.RetryBackoff,
+		},
+		e.send,
+		"Failed to write spans to Google Cloud Trace")
+	return e, nil
+}
+
+// ExportSpan adds a finished span to the current batch.
+// Spans which get exported after Close are written immediately.
+func (e *CloudTraceExporter) ExportSpan(span SpanData) {
+	e.batcher.Add(span)
+}
+
+// Dropped returns the number of spans which have been discarded due to a full buffer.
+func (e *CloudTraceExporter) Dropped() uint64 {
+	return e.batcher.Dropped()
+}
+
+// Flush writes all collected spans in batches to the Cloud Trace API.
+// Spans of a batch which could not be written after all retries are discarded.
+func (e *CloudTraceExporter) Flush(ctx context.Context) error {
+	return e.batcher.Flush(ctx)
+}
+
+// Close stops the background flushing and writes all remaining spans.
+func (e *CloudTraceExporter) Close(ctx context.Context) error {
+	return e.batcher.Close(ctx)
+}
+
+func (e *CloudTraceExporter) send(ctx context.Context, items []interface{}) error {
+	spans := make([]SpanData, len(items))
+	for i, item := range items {
+		spans[i] = item.(SpanData)
+	}
+	body, err := FormatCloudTraceBatch(e.projectID, spans)
+	if err != nil {
+		return fmt.Errorf("error serializing spans: %w", err)
+	}
+	return e.batcher.Post(ctx, e.request, body)
+}
@@ -0,0 +1,160 @@
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testSpanData() SpanData {
+	sc, _ := ParseTraceparent("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	parentSpanID, _ := ParseOpenTelemetrySpanID("00f067aa0ba902b7")
+	start := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	return SpanData{
+		Name:         "GET /foo",
+		SpanContext:  sc,
+		ParentSpanID: parentSpanID,
+		StartTime:    start,
+		EndTime:      start.Add(1500 * time.Millisecond),
+		Attributes:   map[string]interface{}{"http.status_code": 200},
+		Status:       Status{Code: StatusError, Message: "boom"},
+		Events:       []SpanEvent{{Name: "cache miss", Time: start.Add(time.Second)}},
+	}
+}
+
+func Test_FormatCloudTraceBatch_FormatsCorrectly(t *testing.T) {
+	body, err := FormatCloudTraceBatch("my-project", []SpanData{testSpanData()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"spans":[{"name":"projects/my-project/traces/0af7651916cd43dd8448eb211c80319c/spans/b7ad6b7169203331","spanId":"b7ad6b7169203331","parentSpanId":"00f067aa0ba902b7","displayName":{"value":"GET /foo","truncatedByteCount":0},"startTime":"2021-03-04T05:06:07Z","endTime":"2021-03-04T05:06:08.5Z","attributes":{"attributeMap":{"http.status_code":{"intValue":"200"}}},"timeEvents":{"timeEvent":[{"time":"2021-03-04T05:06:08Z","annotation":{"description":{"value":"cache miss","truncatedByteCount":0}}}]},"status":{"code":2,"message":"boom"}}]}`
+	if string(body) != expected {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, body)
+	}
+}
+
+func Test_CloudTraceExporter_Close_WritesSpans(t *testing.T) {
+	var mutex sync.Mutex
+	var paths []string
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mutex.Lock()
+		defer mutex.Unlock()
+		paths = append(paths, r.URL.Path)
+		bodies = append(bodies, string(body))
+		_, _ = w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	exporter, err := NewCloudTraceExporter(server.Client(), "my-project", CloudTraceOptions{Endpoint: server.URL + "/v2", BatchSize: 2, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		exporter.ExportSpan(testSpanData())
+	}
+	if err := exporter.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	total := 0
+	for i, body := range bodies {
+		if paths[i] != "/v2/projects/my-project/traces:batchWrite" {
+			t.Errorf("Unexpected path: %s", paths[i])
+		}
+		req := batchWriteRequest{}
+		if err := json.Unmarshal([]byte(body), &req); err != nil {
+			t.Fatal(err)
+		}
+		total += len(req.Spans)
+	}
+	if total != 3 {
+		t.Errorf("Expected 3 spans, but got %d.", total)
+	}
+}
+
+func Test_CloudTraceExporter_Flush_ReturnsAPIErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte("permission denied"))
+	}))
+	defer server.Close()
+
+	exporter, err := NewCloudTraceExporter(server.Client(), "p", CloudTraceOptions{Endpoint: server.URL, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer exporter.Close(context.Background())
+
+	exporter.ExportSpan(testSpanData())
+	if err := exporter.Flush(context.Background()); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("Expected an API error, but got %v", err)
+	}
+}
+
+func Test_CloudTraceExporter_Flush_RetriesFailedRequests(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	exporter, err := NewCloudTraceExporter(server.Client(), "p", CloudTraceOptions{Endpoint: server.URL, FlushInterval: time.Hour, RetryBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer exporter.Close(context.Background())
+
+	exporter.ExportSpan(testSpanData())
+	if err := exporter.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if actual := atomic.LoadInt32(&attempts); actual != 3 {
+		t.Errorf("Expected 3 attempts, but got %d.", actual)
+	}
+}
+
+func Test_NewCloudTraceExporter_EmptyProjectID_ReturnsError(t *testing.T) {
+	if _, err := NewCloudTraceExporter(nil, "", CloudTraceOptions{}); err == nil {
+		t.Error("Expected an error for an empty project ID.")
+	}
+}
+
+func Test_Truncate_KeepsUTF8Characters(t *testing.T) {
+	type testCase struct {
+		Value     string
+		MaxLength int
+		Expected  truncatableString
+	}
+
+	testCases := []testCase{
+		{"abc", 3, truncatableString{Value: "abc"}},
+		{"abcd", 3, truncatableString{Value: "abc", TruncatedByteCount: 1}},
+		{"aä", 2, truncatableString{Value: "a", TruncatedByteCount: 2}},
+		{"a€b", 3, truncatableString{Value: "a", TruncatedByteCount: 4}},
+		{"a€b", 4, truncatableString{Value: "a€", TruncatedByteCount: 1}},
+	}
+
+	for _, testCase := range testCases {
+		if actual := truncate(testCase.Value, testCase.MaxLength); actual != testCase.Expected {
+			t.Errorf("\nExpected:\n%+v,\nActual:\n%+v", testCase.Expected, actual)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package trace
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// HeaderFormat identifies a HTTP header format which propagates trace information.
+type HeaderFormat int
+
+const (
+	// W3CFormat is the W3C traceparent and tracestate header format.
+	W3CFormat HeaderFormat = iota
+	// CloudTraceFormat is the Google Cloud X-Cloud-Trace-Context header format.
+	CloudTraceFormat
+)
+
+// Propagator reads and writes trace information from and to HTTP headers.
+type Propagator struct {
+	formats []HeaderFormat
+}
+
+// NewPropagator creates a new propagator for the given header formats.
+// The order of the formats determines their priority when reading trace information.
+func NewPropagator(formats ...HeaderFormat) Propagator {
+	return Propagator{formats: append([]HeaderFormat(nil), formats...)}
+}
+
+// DefaultPropagator prefers the W3C traceparent header and falls back to the X-Cloud-Trace-Context header.
+var DefaultPropagator = NewPropagator(W3CFormat, CloudTraceFormat)
+
+// Extract returns the span context of the first header format in order of priority
+// which is present and valid.
+func (p Propagator) Extract(header http.Header) (SpanContext, bool) {
+	for _, format := range p.formats {
+		switch format {
+		case W3CFormat:
+			value := header.Get(TraceparentHeader)
+			if len(value) == 0 {
+				continue
+			}
+			sc, err := ParseTraceparent(value)
+			if err != nil {
+				continue
+			}
+			if states := header.Values(TracestateHeader); len(states) > 0 {
+				if ts, err := ParseTraceState(strings.Join(states, ",")); err == nil {
+					sc.State = ts
+				}
+			}
+			return sc, true
+		case CloudTraceFormat:
+			value := header.Get(CloudTraceContextHeader)
+			if len(value) == 0 {
+				continue
+			}
+			if sc, err := ParseCloudTraceContext(value); err == nil {
+				return sc, true
+			}
+		}
+	}
+	return SpanContext{}, false
+}
+
+// Inject writes the span context in all header formats of the propagator.
+func (p Propagator) Inject(sc SpanContext, header http.Header) {
+	if !sc.IsValid() {
+		return
+	}
+	for _, format := range p.formats {
+		switch format {
+		case W3CFormat:
+			header.Set(TraceparentHeader, FormatTraceparent(sc))
+			if sc.State.Len() > 0 {
+				header.Set(TracestateHeader, sc.State.String())
+			} else {
+				header.Del(TracestateHeader)
+			}
+		case CloudTraceFormat:
+			header.Set(CloudTraceContextHeader, FormatCloudTraceContext(sc))
+		}
+	}
+}
+
+// InjectContext writes the span context of ctx in all header formats of the propagator.
+func (p Propagator) InjectContext(ctx context.Context, header http.Header) {
+	if sc, ok := TryGetSpanContext(ctx); ok {
+		p.Inject(sc, header)
+	}
+}
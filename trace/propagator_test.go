@@ -0,0 +1,105 @@
+package trace
+
+import (
+	"net/http"
+	"testing"
+)
+
+const (
+	testTraceparent  = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	testCloudContext = "105445aa7843bc8bf206b12000100000/2205310701640571284;o=0"
+)
+
+func Test_Propagator_Extract_RespectsPriority(t *testing.T) {
+	header := http.Header{}
+	header.Set(TraceparentHeader, testTraceparent)
+	header.Add(TracestateHeader, "rojo=00f067aa0ba902b7")
+	header.Add(TracestateHeader, "congo=t61rcWkgMzE")
+	header.Set(CloudTraceContextHeader, testCloudContext)
+
+	sc, ok := NewPropagator(W3CFormat, CloudTraceFormat).Extract(header)
+	if !ok || sc.TraceID.String() != "0af7651916cd43dd8448eb211c80319c" || !sc.IsSampled() {
+		t.Errorf("Expected the traceparent header to win, but got %+v", sc)
+	}
+	if sc.State.String() != "rojo=00f067aa0ba902b7,congo=t61rcWkgMzE" {
+		t.Errorf("Unexpected trace state: %s", sc.State)
+	}
+
+	sc, ok = NewPropagator(CloudTraceFormat, W3CFormat).Extract(header)
+	if !ok || sc.TraceID.String() != "105445aa7843bc8bf206b12000100000" || sc.IsSampled() {
+		t.Errorf("Expected the X-Cloud-Trace-Context header to win, but got %+v", sc)
+	}
+}
+
+func Test_Propagator_Extract_FallsBackOnInvalidHeader(t *testing.T) {
+	header := http.Header{}
+	header.Set(TraceparentHeader, "invalid")
+	header.Set(CloudTraceContextHeader, testCloudContext)
+
+	sc, ok := DefaultPropagator.Extract(header)
+	if !ok || sc.TraceID.String() != "105445aa7843bc8bf206b12000100000" {
+		t.Errorf("Expected the X-Cloud-Trace-Context header to be used, but got %+v", sc)
+	}
+
+	if _, ok := NewPropagator(W3CFormat).Extract(header); ok {
+		t.Error("Expected no span context from an invalid traceparent header.")
+	}
+}
+
+func Test_Propagator_Inject_WritesAllFormats(t *testing.T) {
+	sc, err := ParseTraceparent(testTraceparent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := http.Header{}
+	DefaultPropagator.Inject(sc, header)
+
+	if actual := header.Get(TraceparentHeader); actual != testTraceparent {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", testTraceparent, actual)
+	}
+	if actual := header.Get(CloudTraceContextHeader); actual != FormatCloudTraceContext(sc) {
+		t.Errorf("Unexpected X-Cloud-Trace-Context header: %s", actual)
+	}
+
+	extracted, ok := NewPropagator(CloudTraceFormat).Extract(header)
+	if !ok || extracted.TraceID != sc.TraceID || extracted.SpanID != sc.SpanID || !extracted.IsSampled() {
+		t.Errorf("Expected the X-Cloud-Trace-Context header to round trip, but got %+v", extracted)
+	}
+}
+
+func Test_Propagator_CloudTraceContextToTraceparent_KeepsSpanID(t *testing.T) {
+	header := http.Header{}
+	header.Set(CloudTraceContextHeader, "105445aa7843bc8bf206b12000100000/1;o=1")
+
+	sc, ok := NewPropagator(CloudTraceFormat).Extract(header)
+	if !ok {
+		t.Fatal("Expected the X-Cloud-Trace-Context header to be extracted.")
+	}
+
+	outgoing := http.Header{}
+	NewPropagator(W3CFormat).Inject(sc, outgoing)
+
+	expected := "00-105445aa7843bc8bf206b12000100000-0000000000000001-01"
+	if actual := outgoing.Get(TraceparentHeader); actual != expected {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
+	}
+}
+
+func Test_Propagator_TraceparentToCloudTraceContext_KeepsSpanID(t *testing.T) {
+	header := http.Header{}
+	header.Set(TraceparentHeader, testTraceparent)
+
+	sc, ok := NewPropagator(W3CFormat).Extract(header)
+	if !ok {
+		t.Fatal("Expected the traceparent header to be extracted.")
+	}
+
+	outgoing := http.Header{}
+	NewPropagator(CloudTraceFormat).Inject(sc, outgoing)
+
+	expected := "0af7651916cd43dd8448eb211c80319c/13235353014750950193;o=1"
+	if actual := outgoing.Get(CloudTraceContextHeader); actual != expected {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
+	}
+}
@@ -0,0 +1,165 @@
+package trace
+
+import (
+	"encoding/binary"
+	"math"
+	"sync"
+	"time"
+)
+
+// SamplingParameters holds the information which a sampler can base its decision on.
+type SamplingParameters struct {
+	// Parent is the span context of the parent span, if HasParent is true.
+	Parent    SpanContext
+	HasParent bool
+	TraceID   ID
+	Name      string
+}
+
+// Sampler decides if a trace gets sampled.
+type Sampler interface {
+	ShouldSample(SamplingParameters) bool
+}
+
+// --------------------------------
+// Samplers
+// --------------------------------
+
+// AlwaysOnSampler samples every trace.
+type AlwaysOnSampler struct{}
+
+// ShouldSample always returns true.
+func (s *AlwaysOnSampler) ShouldSample(_ SamplingParameters) bool {
+	return true
+}
+
+// AlwaysOffSampler samples no trace.
+type AlwaysOffSampler struct{}
+
+// ShouldSample always returns false.
+func (s *AlwaysOffSampler) ShouldSample(_ SamplingParameters) bool {
+	return false
+}
+
+// RatioSampler samples a fraction of all traces.
+// The decision is deterministic on the trace ID, so that all services
+// which use the same ratio make the same decision for a trace.
+type RatioSampler struct {
+	bound uint64
+}
+
+// NewRatioSampler creates a new sampler which samples the given fraction (0 to 1) of all traces.
+func NewRatioSampler(fraction float64) *RatioSampler {
+	if fraction >= 1 {
+		return &RatioSampler{bound: math.MaxUint64}
+	}
+	if fraction <= 0 {
+		return &RatioSampler{bound: 0}
+	}
+	return &RatioSampler{bound: uint64(fraction * (1 << 63))}
+}
+
+// ShouldSample compares the lower 63 bits of the last 8 bytes of the trace ID against the ratio.
+func (s *RatioSampler) ShouldSample(p SamplingParameters) bool {
+	if s.bound == math.MaxUint64 {
+		return true
+	}
+	x := binary.BigEndian.Uint64(p.TraceID[8:16]) >> 1
+	return x < s.bound
+}
+
+// ParentBasedSampler follows the decision of the parent span and
+// delegates the decision for root spans to another sampler.
+type ParentBasedSampler struct {
+	root Sampler
+}
+
+// NewParentBasedSampler creates a new sampler which uses the root sampler for traces without a parent.
+func NewParentBasedSampler(root Sampler) *ParentBasedSampler {
+	if root == nil {
+		root = &AlwaysOnSampler{}
+	}
+	return &ParentBasedSampler{root: root}
+}
+
+// ShouldSample returns the parent's decision or the root sampler's decision.
+func (s *ParentBasedSampler) ShouldSample(p SamplingParameters) bool {
+	if p.HasParent {
+		return p.Parent.IsSampled()
+	}
+	return s.root.ShouldSample(p)
+}
+
+// RateLimitingSampler samples at most a given number of traces per second.
+type RateLimitingSampler struct {
+	mutex      sync.Mutex
+	perSecond  float64
+	tokens     float64
+	lastRefill time.Time
+	now        func() time.Time
+}
+
+// NewRateLimitingSampler creates a new sampler which samples at most perSecond traces per second.
+// A rate of zero or less never samples and a fraction samples the first trace once a whole token has accrued.
+func NewRateLimitingSampler(perSecond float64) *RateLimitingSampler {
+	return &RateLimitingSampler{
+		perSecond:  perSecond,
+		tokens:     math.Min(perSecond, 1),
+		lastRefill: time.Now(),
+		now:        time.Now,
+	}
+}
+
+// ShouldSample returns true as long as the rate limit has not been exceeded.
+func (s *RateLimitingSampler) ShouldSample(_ SamplingParameters) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.perSecond <= 0 {
+		return false
+	}
+	now := s.now()
+	s.tokens += now.Sub(s.lastRefill).Seconds() * s.perSecond
+	if limit := math.Max(s.perSecond, 1); s.tokens > limit {
+		s.tokens = limit
+	}
+	s.lastRefill = now
+
+	if s.tokens < 1 {
+		return false
+	}
+	s.tokens--
+	return true
+}
+
+// --------------------------------
+// Default Sampler
+// --------------------------------
+
+// DefaultSampler decides if new spans get sampled.
+// It follows the parent's decision and samples every new trace.
+// It should be set once at application start up.
+var DefaultSampler Sampler = NewParentBasedSampler(&AlwaysOnSampler{})
+
+// NewSpanContext returns a span context with a new span ID. If the parent is valid the span
+// context continues the parent's trace, otherwise it starts a new trace.
+// The sampled flag is set by the DefaultSampler.
+func NewSpanContext(parent SpanContext, name string) SpanContext {
+	sc := parent
+	hasParent := parent.TraceID.IsValid()
+	if hasParent {
+		sc.SpanID = DefaultGenerator.NewSpanID()
+	} else {
+		sc = SpanContext{}
+		sc.TraceID, sc.SpanID = DefaultGenerator.NewTraceIDs()
+	}
+
+	sampled := DefaultSampler.ShouldSample(SamplingParameters{
+		Parent:    parent,
+		HasParent: hasParent,
+		TraceID:   sc.TraceID,
+		Name:      name,
+	})
+	sc.Flags = sc.Flags.WithSampled(sampled)
+	return sc
+}
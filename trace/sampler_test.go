@@ -0,0 +1,113 @@
+package trace
+
+import (
+	"testing"
+	"time"
+)
+
+func useSampler(t *testing.T, sampler Sampler) {
+	previous := DefaultSampler
+	DefaultSampler = sampler
+	t.Cleanup(func() { DefaultSampler = previous })
+}
+
+func Test_RatioSampler_IsDeterministicAndRespectsRatio(t *testing.T) {
+	sampler := NewRatioSampler(0.25)
+
+	sampled := 0
+	for i := 0; i < 10000; i++ {
+		traceID, _ := DefaultGenerator.NewTraceIDs()
+		p := SamplingParameters{TraceID: traceID}
+		decision := sampler.ShouldSample(p)
+		if decision != sampler.ShouldSample(p) {
+			t.Fatal("Expected the same decision for the same trace ID.")
+		}
+		if decision {
+			sampled++
+		}
+	}
+
+	if sampled < 2200 || sampled > 2800 {
+		t.Errorf("Expected roughly 2500 sampled traces, but got %d.", sampled)
+	}
+
+	traceID, _ := DefaultGenerator.NewTraceIDs()
+	if !NewRatioSampler(1).ShouldSample(SamplingParameters{TraceID: traceID}) || NewRatioSampler(0).ShouldSample(SamplingParameters{TraceID: traceID}) {
+		t.Error("Expected a ratio of 1 to sample and a ratio of 0 to not sample.")
+	}
+}
+
+func Test_ParentBasedSampler_FollowsParent(t *testing.T) {
+	sampler := NewParentBasedSampler(&AlwaysOffSampler{})
+
+	sampledParent := SpanContext{Flags: FlagSampled}
+	if !sampler.ShouldSample(SamplingParameters{Parent: sampledParent, HasParent: true}) {
+		t.Error("Expected to follow the sampled parent.")
+	}
+	if sampler.ShouldSample(SamplingParameters{Parent: SpanContext{}, HasParent: true}) {
+		t.Error("Expected to follow the parent which is not sampled.")
+	}
+	if sampler.ShouldSample(SamplingParameters{}) {
+		t.Error("Expected the root sampler to decide for root spans.")
+	}
+}
+
+func Test_RateLimitingSampler_LimitsRate(t *testing.T) {
+	now := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	sampler := NewRateLimitingSampler(2)
+	sampler.lastRefill = now
+	sampler.now = func() time.Time { return now }
+
+	if !sampler.ShouldSample(SamplingParameters{}) || sampler.ShouldSample(SamplingParameters{}) {
+		t.Error("Expected 1 sampled trace at the start.")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if !sampler.ShouldSample(SamplingParameters{}) || sampler.ShouldSample(SamplingParameters{}) {
+		t.Error("Expected 1 sampled trace after half a second.")
+	}
+
+	now = now.Add(time.Second)
+	decisions := []bool{}
+	for i := 0; i < 3; i++ {
+		decisions = append(decisions, sampler.ShouldSample(SamplingParameters{}))
+	}
+	if !decisions[0] || !decisions[1] || decisions[2] {
+		t.Errorf("Expected 2 sampled traces after a second, but got %v", decisions)
+	}
+
+	for _, perSecond := range []float64{0, -1} {
+		sampler = NewRateLimitingSampler(perSecond)
+		sampler.lastRefill = now
+		sampler.now = func() time.Time { return now.Add(time.Hour) }
+		if sampler.ShouldSample(SamplingParameters{}) {
+			t.Errorf("Expected no sampled traces at a rate of %v.", perSecond)
+		}
+	}
+
+	sampler = NewRateLimitingSampler(0.5)
+	sampler.lastRefill = now
+	sampler.now = func() time.Time { return now }
+	if sampler.ShouldSample(SamplingParameters{}) {
+		t.Error("Expected no sampled trace at the start at a rate of 0.5.")
+	}
+	now = now.Add(2 * time.Second)
+	if !sampler.ShouldSample(SamplingParameters{}) || sampler.ShouldSample(SamplingParameters{}) {
+		t.Error("Expected 1 sampled trace after two seconds at a rate of 0.5.")
+	}
+}
+
+func Test_NewSpanContext_AppliesDefaultSampler(t *testing.T) {
+	useSampler(t, NewParentBasedSampler(&AlwaysOffSampler{}))
+
+	root := NewSpanContext(SpanContext{}, "root")
+	if !root.IsValid() || root.IsSampled() {
+		t.Errorf("Expected a valid root span context which is not sampled, but got %+v", root)
+	}
+
+	parent, _ := ParseTraceparent("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	child := NewSpanContext(parent, "child")
+	if child.TraceID != parent.TraceID || child.SpanID == parent.SpanID || !child.IsSampled() {
+		t.Errorf("Expected a sampled child span context, but got %+v", child)
+	}
+}
@@ -0,0 +1,198 @@
+package trace
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// --------------------------------
+// Status
+// --------------------------------
+
+// StatusCode denotes the outcome of a span.
+type StatusCode int
+
+const (
+	// StatusUnset means that the outcome of the span has not been set.
+	StatusUnset StatusCode = iota
+	// StatusOK means that the span completed successfully.
+	StatusOK
+	// StatusError means that the span completed with an error.
+	StatusError
+)
+
+// Status is the outcome of a span.
+type Status struct {
+	Code    StatusCode
+	Message string
+}
+
+// --------------------------------
+// SpanData
+// --------------------------------
+
+// SpanEvent is a time stamped annotation of a span.
+type SpanEvent struct {
+	Name       string
+	Time       time.Time
+	Attributes map[string]interface{}
+}
+
+// SpanData is a read-only view of a finished span which gets passed to a SpanExporter.
+type SpanData struct {
+	Name         string
+	SpanContext  SpanContext
+	ParentSpanID SpanID
+	StartTime    time.Time
+	EndTime      time.Time
+	Attributes   map[string]interface{}
+	Status       Status
+	Events       []SpanEvent
+}
+
+// SpanExporter receives finished spans.
+type SpanExporter interface {
+	ExportSpan(SpanData)
+}
+
+// NoSpanExporter discards all spans.
+type NoSpanExporter struct{}
+
+// ExportSpan does nothing.
+func (e *NoSpanExporter) ExportSpan(_ SpanData) {}
+
+// DefaultSpanExporter receives all spans which have been started with StartSpan.
+// It should be set once at application start up.
+var DefaultSpanExporter SpanExporter = &NoSpanExporter{}
+
+// --------------------------------
+// Span
+// --------------------------------
+
+// Span records the timing and details of a single operation within a trace.
+// All methods are safe for concurrent use.
+type Span struct {
+	mutex    sync.Mutex
+	data     SpanData
+	ended    bool
+	exporter SpanExporter
+}
+
+// Custom type to avoid key collisions in the context object.
+type spanKey int
+
+// SpanKey is the key that references the current span inside context.
+const SpanKey spanKey = 0
+
+// StartSpan starts a new span as a child of the span context inside ctx.
+// If ctx has no span context then the span starts a new trace.
+// The DefaultSampler decides if the span gets sampled.
+// The returned context holds the new span and its span context.
+func StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	parent, ok := TryGetSpanContext(ctx)
+	if !ok {
+		// Trace IDs without a span context carry no sampling decision.
+		parent.TraceID, _ = TryGetID(ctx)
+		parent.SpanID, _ = TryGetSpanID(ctx)
+		parent.Flags = FlagSampled
+	}
+
+	sc := NewSpanContext(parent, name)
+	parentSpanID := SpanID{}
+	if parent.TraceID.IsValid() {
+		parentSpanID = parent.SpanID
+	}
+
+	span := &Span{
+		data: SpanData{
+			Name:         name,
+			SpanContext:  sc,
+			ParentSpanID: parentSpanID,
+			StartTime:    time.Now().UTC(),
+		},
+		exporter: DefaultSpanExporter,
+	}
+
+	ctx = ContextWithSpanContext(ctx, sc)
+	ctx = context.WithValue(ctx, SpanKey, span)
+	return ctx, span
+}
+
+// SpanFromContext returns the current span or nil if there is none.
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	if span, ok := ctx.Value(SpanKey).(*Span); ok {
+		return span
+	}
+	return nil
+}
+
+// SpanContext returns the span context of the span.
+func (s *Span) SpanContext() SpanContext {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.data.SpanContext
+}
+
+// SetAttribute sets an attribute of the span.
+// Values should be a string, an integer or a boolean.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.ended {
+		return
+	}
+	if s.data.Attributes == nil {
+		s.data.Attributes = make(map[string]interface{})
+	}
+	s.data.Attributes[key] = value
+}
+
+// AddEvent adds a time stamped event to the span.
+func (s *Span) AddEvent(name string, attributes map[string]interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.ended {
+		return
+	}
+	s.data.Events = append(s.data.Events, SpanEvent{
+		Name:       name,
+		Time:       time.Now().UTC(),
+		Attributes: attributes,
+	})
+}
+
+// SetStatus sets the outcome of the span.
+func (s *Span) SetStatus(code StatusCode, message string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.ended {
+		return
+	}
+	s.data.Status = Status{Code: code, Message: message}
+}
+
+// End finishes the span and hands it to the span exporter if it is sampled.
+// Calling End more than once has no effect.
+func (s *Span) End() {
+	s.mutex.Lock()
+	if s.ended {
+		s.mutex.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.EndTime = time.Now().UTC()
+	data := s.data
+	s.mutex.Unlock()
+
+	if data.SpanContext.IsSampled() && s.exporter != nil {
+		s.exporter.ExportSpan(data)
+	}
+}
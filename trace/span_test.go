@@ -0,0 +1,91 @@
+package trace
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+type recordingSpanExporter struct {
+	mutex sync.Mutex
+	spans []SpanData
+}
+
+func (e *recordingSpanExporter) ExportSpan(span SpanData) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.spans = append(e.spans, span)
+}
+
+func useSpanExporter(t *testing.T, exporter SpanExporter) {
+	previous := DefaultSpanExporter
+	DefaultSpanExporter = exporter
+	t.Cleanup(func() { DefaultSpanExporter = previous })
+}
+
+func Test_StartSpan_CreatesChildSpans(t *testing.T) {
+	exporter := &recordingSpanExporter{}
+	useSpanExporter(t, exporter)
+
+	ctx, root := StartSpan(context.Background(), "root")
+	childCtx, child := StartSpan(ctx, "child")
+
+	if SpanFromContext(childCtx) != child || SpanFromContext(ctx) != root {
+		t.Error("Expected the spans to be stored in the context.")
+	}
+
+	rootSC, childSC := root.SpanContext(), child.SpanContext()
+	if !rootSC.IsValid() || !rootSC.IsSampled() {
+		t.Errorf("Expected a valid sampled root span context, but got %+v", rootSC)
+	}
+	if childSC.TraceID != rootSC.TraceID || childSC.SpanID == rootSC.SpanID {
+		t.Errorf("Expected the child to share the trace ID with a new span ID, but got %+v", childSC)
+	}
+	if sc, _ := TryGetSpanContext(childCtx); sc.SpanID != childSC.SpanID {
+		t.Error("Expected the child span context to be stored in the context.")
+	}
+
+	child.SetAttribute("http.status_code", 200)
+	child.AddEvent("cache miss", map[string]interface{}{"key": "abc"})
+	child.SetStatus(StatusError, "boom")
+	child.End()
+	child.End()
+	root.End()
+
+	if len(exporter.spans) != 2 {
+		t.Fatalf("Expected 2 exported spans, but got %d.", len(exporter.spans))
+	}
+
+	data := exporter.spans[0]
+	if data.Name != "child" ||
+		data.ParentSpanID != rootSC.SpanID ||
+		data.Attributes["http.status_code"] != 200 ||
+		len(data.Events) != 1 ||
+		data.Status.Code != StatusError ||
+		data.EndTime.Before(data.StartTime) {
+		t.Errorf("Unexpected span data: %+v", data)
+	}
+	if exporter.spans[1].ParentSpanID.IsValid() {
+		t.Error("Expected the root span to have no parent.")
+	}
+}
+
+func Test_StartSpan_ContinuesIncomingTrace(t *testing.T) {
+	exporter := &recordingSpanExporter{}
+	useSpanExporter(t, exporter)
+
+	parent, err := ParseTraceparent("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-00")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, span := StartSpan(ContextWithSpanContext(context.Background(), parent), "not sampled")
+	span.End()
+
+	if span.SpanContext().TraceID != parent.TraceID {
+		t.Error("Expected the span to continue the incoming trace.")
+	}
+	if len(exporter.spans) != 0 {
+		t.Error("Expected spans of a trace which is not sampled to be discarded.")
+	}
+}
@@ -46,9 +46,10 @@ func (id SpanID) String() string {
 	return hex.EncodeToString(id[:])
 }
 
-// Decimal returns the decimal representation of the ID.
+// Decimal returns the decimal representation of the ID as used by the X-Cloud-Trace-Context header.
+// The bytes are read in big-endian order, so that the hex and decimal forms denote the same number.
 func (id SpanID) Decimal() uint64 {
-	return binary.LittleEndian.Uint64(id[:])
+	return binary.BigEndian.Uint64(id[:])
 }
 
 // IDGenerator allows custom generators for TraceID and SpanID.
@@ -121,16 +122,17 @@ func ParseOpenTelemetrySpanID(value string) (SpanID, error) {
 	return id, nil
 }
 
-// ParseGoogleCloudSpanID returns a span ID from a string holding an unsigned int64 value.
+// ParseGoogleCloudSpanID returns a span ID from a string holding an unsigned decimal int64 value,
+// which gets stored in big-endian order like the hex representation of a W3C span ID.
 func ParseGoogleCloudSpanID(value string) (SpanID, error) {
 	id := SpanID{}
 
-	num, err := strconv.ParseUint(value, 0, 64)
+	num, err := strconv.ParseUint(value, 10, 64)
 	if err != nil {
 		return id, fmt.Errorf("error paring Google Cloud SpanID to uint64: %w", err)
 	}
 
-	binary.LittleEndian.PutUint64(id[:], num)
+	binary.BigEndian.PutUint64(id[:], num)
 
 	if !id.IsValid() {
 		return id, errors.New("invalid/empty span ID")
@@ -185,6 +187,7 @@ var DefaultGenerator = defaultIDGenerator()
 // Custom types to avoid key collisions in the context object.
 type traceIDKey int
 type spanIDKey int
+type spanContextKey int
 
 // IDKey is the key that references the trace ID inside context.
 const IDKey traceIDKey = 0
@@ -192,6 +195,9 @@ const IDKey traceIDKey = 0
 // SpanIDKey is the key that references the span ID inside context.
 const SpanIDKey spanIDKey = 0
 
+// SpanContextKey is the key that references the span context inside context.
+const SpanContextKey spanContextKey = 0
+
 // TryGetID tries to get a previously saved trace ID.
 func TryGetID(ctx context.Context) (ID, bool) {
 	if ctx == nil {
@@ -220,3 +226,21 @@ func Context(ctx context.Context, traceID ID, spanID SpanID) context.Context {
 	ctx = context.WithValue(ctx, SpanIDKey, spanID)
 	return ctx
 }
+
+// TryGetSpanContext tries to get a previously saved span context.
+func TryGetSpanContext(ctx context.Context) (SpanContext, bool) {
+	if ctx == nil {
+		return SpanContext{}, false
+	}
+	if sc, ok := ctx.Value(SpanContextKey).(SpanContext); ok {
+		return sc, true
+	}
+	return SpanContext{}, false
+}
+
+// ContextWithSpanContext adds a span context, as well as its trace ID and span ID, to the current context.
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	ctx = Context(ctx, sc.TraceID, sc.SpanID)
+	ctx = context.WithValue(ctx, SpanContextKey, sc)
+	return ctx
+}
@@ -1,6 +1,7 @@
 package trace
 
 import (
+	"context"
 	"testing"
 )
 
@@ -20,3 +21,22 @@ func Test_SpanID(t *testing.T) {
 		t.Errorf("\nExpected:\n%d,\nActual:\n%d", expected, actual)
 	}
 }
+
+func Test_ContextWithSpanContext_StoresAllIDs(t *testing.T) {
+	sc, err := ParseTraceparent("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := ContextWithSpanContext(context.Background(), sc)
+
+	if actual, ok := TryGetSpanContext(ctx); !ok || actual.TraceID != sc.TraceID || actual.SpanID != sc.SpanID || !actual.IsSampled() {
+		t.Errorf("Unexpected span context: %+v", actual)
+	}
+	if traceID, ok := TryGetID(ctx); !ok || traceID != sc.TraceID {
+		t.Errorf("Unexpected trace ID: %s", traceID)
+	}
+	if spanID, ok := TryGetSpanID(ctx); !ok || spanID != sc.SpanID {
+		t.Errorf("Unexpected span ID: %s", spanID)
+	}
+}
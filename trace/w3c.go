@@ -0,0 +1,296 @@
+package trace
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// --------------------------------
+// Flags
+// --------------------------------
+
+// Flags are the trace flags of the W3C Trace Context specification.
+// See more at: https://www.w3.org/TR/trace-context/#trace-flags
+type Flags byte
+
+// FlagSampled denotes that the caller may have recorded trace data.
+const FlagSampled Flags = 0x01
+
+// IsSampled checks if the sampled flag is set.
+func (f Flags) IsSampled() bool {
+	return f&FlagSampled == FlagSampled
+}
+
+// WithSampled returns a copy of the flags with the sampled flag set or unset.
+func (f Flags) WithSampled(sampled bool) Flags {
+	if sampled {
+		return f | FlagSampled
+	}
+	return f &^ FlagSampled
+}
+
+// String returns the hex string representation of the flags.
+func (f Flags) String() string {
+	return fmt.Sprintf("%02x", byte(f))
+}
+
+// --------------------------------
+// SpanContext
+// --------------------------------
+
+// SpanContext holds the trace information which gets propagated between services.
+type SpanContext struct {
+	TraceID ID
+	SpanID  SpanID
+	Flags   Flags
+	State   TraceState
+}
+
+// IsValid checks if the span context has a valid trace ID and span ID.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// IsSampled checks if the sampled flag is set.
+func (sc SpanContext) IsSampled() bool {
+	return sc.Flags.IsSampled()
+}
+
+// --------------------------------
+// traceparent
+// --------------------------------
+
+const (
+	// TraceparentHeader is the name of the W3C traceparent HTTP header.
+	TraceparentHeader = "traceparent"
+	// TracestateHeader is the name of the W3C tracestate HTTP header.
+	TracestateHeader = "tracestate"
+
+	traceparentVersion = "00"
+	traceparentLength  = 55
+)
+
+// ParseTraceparent returns a span context from a W3C traceparent header value.
+// Header values of a future version are parsed as far as they are understood by version 00.
+// See more at: https://www.w3.org/TR/trace-context/#traceparent-header
+func ParseTraceparent(value string) (SpanContext, error) {
+	sc := SpanContext{}
+
+	if len(value) < traceparentLength {
+		return sc, errors.New("cannot parse traceparent because the value is too short")
+	}
+
+	version := [1]byte{}
+	if err := decodeHex(value[0:2], version[:]); err != nil {
+		return sc, fmt.Errorf("cannot parse traceparent version: %w", err)
+	}
+	if version[0] == 0xff {
+		return sc, errors.New("cannot parse traceparent because version ff is forbidden")
+	}
+	if version[0] == 0 && len(value) != traceparentLength {
+		return sc, errors.New("cannot parse traceparent because a version 00 value must be 55 characters long")
+	}
+	if len(value) > traceparentLength && value[traceparentLength] != '-' {
+		return sc, errors.New("cannot parse traceparent because of an invalid separator after the trace flags")
+	}
+	if value[2] != '-' || value[35] != '-' || value[52] != '-' {
+		return sc, errors.New("cannot parse traceparent because of invalid separators")
+	}
+
+	traceID, err := ParseID(value[3:35])
+	if err != nil {
+		return sc, err
+	}
+
+	spanID, err := ParseOpenTelemetrySpanID(value[36:52])
+	if err != nil {
+		return sc, err
+	}
+
+	flags := [1]byte{}
+	if err := decodeHex(value[53:55], flags[:]); err != nil {
+		return sc, fmt.Errorf("cannot parse traceparent flags: %w", err)
+	}
+
+	sc.TraceID = traceID
+	sc.SpanID = spanID
+	sc.Flags = Flags(flags[0])
+	return sc, nil
+}
+
+// FormatTraceparent returns the version 00 W3C traceparent header value of a span context.
+// Flags which are not defined by version 00 are not propagated.
+func FormatTraceparent(sc SpanContext) string {
+	return traceparentVersion + "-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + (sc.Flags & FlagSampled).String()
+}
+
+// --------------------------------
+// tracestate
+// --------------------------------
+
+// MaxTraceStateMembers is the maximum number of list members in a tracestate.
+const MaxTraceStateMembers = 32
+
+type traceStateMember struct {
+	key   string
+	value string
+}
+
+// TraceState is an immutable list of vendor specific key/value pairs
+// which gets propagated alongside the traceparent.
+// See more at: https://www.w3.org/TR/trace-context/#tracestate-header
+type TraceState struct {
+	members []traceStateMember
+}
+
+func isLowerAlpha(c byte) bool {
+	return 'a' <= c && c <= 'z'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isKeyChar(c byte) bool {
+	return isLowerAlpha(c) || isDigit(c) || c == '_' || c == '-' || c == '*' || c == '/'
+}
+
+func validateKeyPart(part string, maxLength int, firstCanBeDigit bool) bool {
+	if len(part) == 0 || len(part) > maxLength {
+		return false
+	}
+	if !isLowerAlpha(part[0]) && !(firstCanBeDigit && isDigit(part[0])) {
+		return false
+	}
+	for i := 1; i < len(part); i++ {
+		if !isKeyChar(part[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func validateTraceStateKey(key string) error {
+	if i := strings.IndexByte(key, '@'); i >= 0 {
+		if validateKeyPart(key[:i], 241, true) && validateKeyPart(key[i+1:], 14, false) {
+			return nil
+		}
+	} else if validateKeyPart(key, 256, false) {
+		return nil
+	}
+	return fmt.Errorf("invalid tracestate key: %q", key)
+}
+
+func validateTraceStateValue(value string) error {
+	if len(value) == 0 || len(value) > 256 || value[len(value)-1] == ' ' {
+		return fmt.Errorf("invalid tracestate value: %q", value)
+	}
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c < 0x20 || c > 0x7e || c == ',' || c == '=' {
+			return fmt.Errorf("invalid tracestate value: %q", value)
+		}
+	}
+	return nil
+}
+
+// ParseTraceState returns a trace state from a W3C tracestate header value.
+// Multiple tracestate headers must be joined with a comma before parsing.
+func ParseTraceState(value string) (TraceState, error) {
+	ts := TraceState{}
+	seen := make(map[string]bool)
+
+	for _, m := range strings.Split(value, ",") {
+		m = strings.Trim(m, " \t")
+		if len(m) == 0 {
+			continue
+		}
+
+		i := strings.IndexByte(m, '=')
+		if i < 0 {
+			return TraceState{}, fmt.Errorf("invalid tracestate member: %q", m)
+		}
+		key, val := m[:i], m[i+1:]
+		if err := validateTraceStateKey(key); err != nil {
+			return TraceState{}, err
+		}
+		if err := validateTraceStateValue(val); err != nil {
+			return TraceState{}, err
+		}
+		if seen[key] {
+			return TraceState{}, fmt.Errorf("duplicate tracestate key: %q", key)
+		}
+		seen[key] = true
+
+		ts.members = append(ts.members, traceStateMember{key: key, value: val})
+		if len(ts.members) > MaxTraceStateMembers {
+			return TraceState{}, errors.New("tracestate has more than 32 list members")
+		}
+	}
+	return ts, nil
+}
+
+// String returns the W3C tracestate header value.
+func (ts TraceState) String() string {
+	var str strings.Builder
+	for i, m := range ts.members {
+		if i > 0 {
+			str.WriteString(",")
+		}
+		str.WriteString(m.key)
+		str.WriteString("=")
+		str.WriteString(m.value)
+	}
+	return str.String()
+}
+
+// Len returns the number of list members.
+func (ts TraceState) Len() int {
+	return len(ts.members)
+}
+
+// Get returns the value of a list member.
+func (ts TraceState) Get(key string) (string, bool) {
+	for _, m := range ts.members {
+		if m.key == key {
+			return m.value, true
+		}
+	}
+	return "", false
+}
+
+// Insert returns a copy of the trace state with the key/value pair added as the left-most list member.
+// An existing member with the same key gets removed and if the list exceeds
+// 32 members then the right-most member gets removed.
+func (ts TraceState) Insert(key, value string) (TraceState, error) {
+	if err := validateTraceStateKey(key); err != nil {
+		return ts, err
+	}
+	if err := validateTraceStateValue(value); err != nil {
+		return ts, err
+	}
+
+	members := make([]traceStateMember, 0, len(ts.members)+1)
+	members = append(members, traceStateMember{key: key, value: value})
+	for _, m := range ts.members {
+		if m.key != key {
+			members = append(members, m)
+		}
+	}
+	if len(members) > MaxTraceStateMembers {
+		members = members[:MaxTraceStateMembers]
+	}
+	return TraceState{members: members}, nil
+}
+
+// Delete returns a copy of the trace state without the list member of the given key.
+func (ts TraceState) Delete(key string) TraceState {
+	members := make([]traceStateMember, 0, len(ts.members))
+	for _, m := range ts.members {
+		if m.key != key {
+			members = append(members, m)
+		}
+	}
+	return TraceState{members: members}
+}
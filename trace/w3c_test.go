@@ -0,0 +1,182 @@
+package trace
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func Test_ParseTraceparent_ValidValues(t *testing.T) {
+	type testCase struct {
+		Value   string
+		TraceID string
+		SpanID  string
+		Sampled bool
+	}
+
+	testCases := []testCase{
+		{"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", "0af7651916cd43dd8448eb211c80319c", "b7ad6b7169203331", true},
+		{"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-00", "0af7651916cd43dd8448eb211c80319c", "b7ad6b7169203331", false},
+		{"01-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-03", "0af7651916cd43dd8448eb211c80319c", "b7ad6b7169203331", true},
+		{"cc-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01-what-the-future-will-be", "0af7651916cd43dd8448eb211c80319c", "b7ad6b7169203331", true},
+	}
+
+	for _, testCase := range testCases {
+		sc, err := ParseTraceparent(testCase.Value)
+		if err != nil {
+			t.Errorf("Failed to parse %s: %v", testCase.Value, err)
+			continue
+		}
+		if sc.TraceID.String() != testCase.TraceID || sc.SpanID.String() != testCase.SpanID || sc.IsSampled() != testCase.Sampled {
+			t.Errorf("Unexpected span context for %s: %+v", testCase.Value, sc)
+		}
+	}
+}
+
+func Test_ParseTraceparent_InvalidValues(t *testing.T) {
+	testCases := []string{
+		"",
+		"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331",
+		"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01-",
+		"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01-extra",
+		"ff-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+		"0g-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+		"00-0AF7651916CD43DD8448EB211C80319C-b7ad6b7169203331-01",
+		"00-00000000000000000000000000000000-b7ad6b7169203331-01",
+		"00-0af7651916cd43dd8448eb211c80319c-0000000000000000-01",
+		"00_0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+		"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-0x",
+		"01-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01x",
+	}
+
+	for _, testCase := range testCases {
+		if sc, err := ParseTraceparent(testCase); err == nil {
+			t.Errorf("Expected an error for %q, but got %+v", testCase, sc)
+		}
+	}
+}
+
+func Test_FormatTraceparent_RoundTrip(t *testing.T) {
+	value := "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	sc, err := ParseTraceparent(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := FormatTraceparent(sc); actual != value {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", value, actual)
+	}
+
+	sc.Flags = sc.Flags.WithSampled(false)
+	expected := "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-00"
+	if actual := FormatTraceparent(sc); actual != expected {
+		t.Errorf("\nExpected:\n%s,\nActual:\n%s", expected, actual)
+	}
+}
+
+func Test_ParseTraceState_ValidValues(t *testing.T) {
+	type testCase struct {
+		Value    string
+		Expected string
+	}
+
+	testCases := []testCase{
+		{"", ""},
+		{"congo=t61rcWkgMzE", "congo=t61rcWkgMzE"},
+		{"rojo=00f067aa0ba902b7,congo=t61rcWkgMzE", "rojo=00f067aa0ba902b7,congo=t61rcWkgMzE"},
+		{" rojo=00f067aa0ba902b7 ,\t, congo=t61rcWkgMzE ", "rojo=00f067aa0ba902b7,congo=t61rcWkgMzE"},
+		{"fw529a3039@dt=abc,1tenant@vendor=x y", "fw529a3039@dt=abc,1tenant@vendor=x y"},
+		{"a/b*c_d-e=1", "a/b*c_d-e=1"},
+	}
+
+	for _, testCase := range testCases {
+		ts, err := ParseTraceState(testCase.Value)
+		if err != nil {
+			t.Errorf("Failed to parse %q: %v", testCase.Value, err)
+			continue
+		}
+		if actual := ts.String(); actual != testCase.Expected {
+			t.Errorf("\nExpected:\n%s,\nActual:\n%s", testCase.Expected, actual)
+		}
+	}
+}
+
+func Test_ParseTraceState_InvalidValues(t *testing.T) {
+	members := make([]string, 33)
+	for i := range members {
+		members[i] = fmt.Sprintf("k%d=v", i)
+	}
+
+	testCases := []string{
+		"novalue",
+		"Upper=1",
+		"1digit=1",
+		"key=",
+		"key=a=b",
+		"a=1,a=2",
+		"tenant@=1",
+		"tenant@Vendor=1",
+		"tenant@vendorvendorvendor=1",
+		strings.Repeat("k", 257) + "=1",
+		strings.Join(members, ","),
+	}
+
+	for _, testCase := range testCases {
+		if ts, err := ParseTraceState(testCase); err == nil {
+			t.Errorf("Expected an error for %q, but got %s", testCase, ts)
+		}
+	}
+}
+
+func Test_TraceState_Mutations(t *testing.T) {
+	ts, err := ParseTraceState("rojo=00f067aa0ba902b7,congo=t61rcWkgMzE")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := ts.Insert("congo", "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := updated.String(); actual != "congo=new,rojo=00f067aa0ba902b7" {
+		t.Errorf("Unexpected tracestate after insert: %s", actual)
+	}
+	if ts.String() != "rojo=00f067aa0ba902b7,congo=t61rcWkgMzE" {
+		t.Error("Trace state has been illegally mutated.")
+	}
+
+	if v, ok := updated.Get("rojo"); !ok || v != "00f067aa0ba902b7" {
+		t.Errorf("Unexpected value for rojo: %s", v)
+	}
+
+	if actual := updated.Delete("rojo").String(); actual != "congo=new" {
+		t.Errorf("Unexpected tracestate after delete: %s", actual)
+	}
+
+	if _, err := ts.Insert("valid", "trailing "); err == nil {
+		t.Error("Expected an error for a value with a trailing space.")
+	}
+	if _, err := ts.Insert("Invalid", "x"); err == nil {
+		t.Error("Expected an error for an invalid key.")
+	}
+}
+
+func Test_TraceState_Insert_RemovesRightMostMemberWhenFull(t *testing.T) {
+	ts := TraceState{}
+	for i := 0; i < MaxTraceStateMembers; i++ {
+		ts, _ = ts.Insert(fmt.Sprintf("k%d", i), "v")
+	}
+
+	ts, err := ts.Insert("new", "v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ts.Len() != MaxTraceStateMembers {
+		t.Errorf("Expected %d members, but got %d.", MaxTraceStateMembers, ts.Len())
+	}
+	if _, ok := ts.Get("k0"); ok {
+		t.Error("Expected the right-most member to be removed.")
+	}
+	if _, ok := ts.Get("new"); !ok {
+		t.Error("Expected the new member to be the left-most member.")
+	}
+}